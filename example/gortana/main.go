@@ -69,10 +69,19 @@ func appRun() {
 		closer.Fatalln(err)
 	}
 	closer.Bind(func() {
-		dec.Destroy()
+		dec.Close()
 	})
-	l := &Listener{
-		dec: dec,
+	l := &Listener{}
+	// ProcessRaw with disabled search because callback needs to be relatime
+	l.rec = sphinx.NewRecognizer(dec, &sphinx.TranscribeOptions{
+		NoSearch:  true,
+		EmitEmpty: true,
+		OnSpeechStart: func() {
+			log.Println("Listening..")
+		},
+	}, l.report)
+	if err := l.rec.Start(); err != nil {
+		closer.Fatalln("[ERR] Sphinx failed to start utterance:", err)
 	}
 
 	var stream *portaudio.Stream
@@ -95,18 +104,13 @@ func appRun() {
 		}
 	})
 
-	if !dec.StartUtt() {
-		closer.Fatalln("[ERR] Sphinx failed to start utterance")
-	}
 	log.Println(banner)
 	log.Println("Ready..")
 	closer.Hold()
 }
 
 type Listener struct {
-	inSpeech   bool
-	uttStarted bool
-	dec        *sphinx.Decoder
+	rec *sphinx.Recognizer
 }
 
 // paCallback: for simplicity reasons we process raw audio with sphinx in the this stream callback,
@@ -120,34 +124,17 @@ func (l *Listener) paCallback(input unsafe.Pointer, _ unsafe.Pointer, sampleCoun
 	)
 
 	in := (*(*[1 << 24]int16)(input))[:int(sampleCount)*channels]
-	// ProcessRaw with disabled search because callback needs to be relatime
-	_, ok := l.dec.ProcessRaw(in, true, false)
-	// log.Printf("processed: %d frames, ok: %v", frames, ok)
-	if !ok {
+	// speech -> silence transitions start new utterances and report results
+	if err := l.rec.Process(in); err != nil {
+		log.Println("[ERR] Sphinx failed to process audio:", err)
 		return statusAbort
 	}
-	if l.dec.IsInSpeech() {
-		l.inSpeech = true
-		if !l.uttStarted {
-			l.uttStarted = true
-			log.Println("Listening..")
-		}
-	} else if l.uttStarted {
-		// speech -> silence transition, time to start new utterance
-		l.dec.EndUtt()
-		l.uttStarted = false
-		l.report() // report results
-		if !l.dec.StartUtt() {
-			closer.Fatalln("[ERR] Sphinx failed to start utterance")
-		}
-	}
 	return statusContinue
 }
 
-func (l *Listener) report() {
-	hyp, _ := l.dec.Hypothesis()
-	if len(hyp) > 0 {
-		log.Printf("    > hypothesis: %s", hyp)
+func (l *Listener) report(u sphinx.Utterance) {
+	if len(u.Text) > 0 {
+		log.Printf("    > hypothesis: %s", u.Text)
 		return
 	}
 	log.Println("ah, nothing")
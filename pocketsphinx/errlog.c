@@ -0,0 +1,24 @@
+#include <stdarg.h>
+#include <stdio.h>
+#include "err.h"
+#include "_cgo_export.h"
+
+// ps_go_err_cb formats the message and passes it to Go, falling back
+// to the default sphinxbase log file writer unless Go has handled it.
+static void ps_go_err_cb(void *user_data, err_lvl_t lvl, const char *fmt, ...)
+{
+    char msg[1024];
+    va_list ap;
+
+    va_start(ap, fmt);
+    vsnprintf(msg, sizeof(msg), fmt, ap);
+    va_end(ap);
+    if (!goErrLog((int)lvl, msg)) {
+        err_logfp_cb(user_data, lvl, "%s", msg);
+    }
+}
+
+void ps_go_err_capture(void)
+{
+    err_set_callback(ps_go_err_cb, NULL);
+}
@@ -0,0 +1,79 @@
+package pocketsphinx
+
+/*
+#cgo pkg-config: pocketsphinx
+void ps_go_err_capture(void);
+*/
+import "C"
+import (
+	"strings"
+	"sync"
+)
+
+// ErrLvl as declared in sphinxbase/err.h:156
+type ErrLvl int32
+
+// ErrLvl enumeration from sphinxbase/err.h:156
+const (
+	ErrDebug    ErrLvl = iota
+	ErrInfo     ErrLvl = 1
+	ErrInfocont ErrLvl = 2
+	ErrWarn     ErrLvl = 3
+	ErrError    ErrLvl = 4
+	ErrFatal    ErrLvl = 5
+)
+
+var logFunc struct {
+	sync.RWMutex
+	fn func(lvl ErrLvl, msg string) bool
+}
+
+var lastErr struct {
+	sync.Mutex
+	msg string
+	seq uint64
+}
+
+// CaptureErrors installs the sphinxbase logging callback that keeps track of
+// the last error message, see LastError. Log messages are still written
+// to the log file set by the -logfn option or to stderr, unless handled by
+// the function set with SetLogFunc.
+func CaptureErrors() {
+	C.ps_go_err_capture()
+}
+
+// SetLogFunc sets the function receiving every formatted sphinxbase log message.
+// If fn returns true, the message is considered handled and is not written to the log file.
+// Pass nil to unset it. Requires CaptureErrors to be called first.
+func SetLogFunc(fn func(lvl ErrLvl, msg string) bool) {
+	logFunc.Lock()
+	logFunc.fn = fn
+	logFunc.Unlock()
+}
+
+// LastError returns the last message of ERROR or FATAL level reported by sphinxbase
+// and its sequence number, which is incremented with every such message.
+// Requires CaptureErrors to be called first.
+func LastError() (msg string, seq uint64) {
+	lastErr.Lock()
+	msg, seq = lastErr.msg, lastErr.seq
+	lastErr.Unlock()
+	return
+}
+
+//export goErrLog
+func goErrLog(lvl C.int, msg *C.char) C.int {
+	if ErrLvl(lvl) >= ErrError {
+		lastErr.Lock()
+		lastErr.msg = strings.TrimSpace(C.GoString(msg))
+		lastErr.seq++
+		lastErr.Unlock()
+	}
+	logFunc.RLock()
+	fn := logFunc.fn
+	logFunc.RUnlock()
+	if fn != nil && fn(ErrLvl(lvl), C.GoString(msg)) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,194 @@
+package pocketsphinx
+
+/*
+#cgo pkg-config: pocketsphinx
+#include "pocketsphinx.h"
+#include <sphinxbase/fe.h>
+#include <stdlib.h>
+#include "cgo_helpers.h"
+
+// fe_go_rows points the rows of a frame block to a contiguous buffer.
+static mfcc_t **fe_go_rows(float32 *cep, int32 nframes, int32 ncep) {
+	mfcc_t **rows;
+	int32 i;
+
+	if (cep == NULL || nframes <= 0)
+		return NULL;
+	rows = malloc(nframes * sizeof(*rows));
+	for (i = 0; i < nframes; i++)
+		rows[i] = (mfcc_t *)(cep + i * ncep);
+	return rows;
+}
+
+static int fe_go_process_frames(fe_t *fe, const int16 *spch, size_t *nsamps,
+                                float32 *cep, int32 *nframes, int32 *frameidx) {
+	mfcc_t **rows = fe_go_rows(cep, *nframes, fe_get_output_size(fe));
+	int rv;
+
+	rv = fe_process_frames(fe, &spch, nsamps, rows, nframes, frameidx);
+	if (rv >= 0 && rows != NULL && *nframes > 0)
+		fe_mfcc_to_float(fe, rows, (float32 **)rows, *nframes);
+	free(rows);
+	return rv;
+}
+
+static int fe_go_process_utt(fe_t *fe, const int16 *spch, size_t nsamps,
+                             void **block, float32 **cep, int32 *nframes) {
+	mfcc_t **rows = NULL;
+	int rv;
+
+	*block = NULL;
+	*cep = NULL;
+	rv = fe_process_utt(fe, spch, nsamps, &rows, nframes);
+	if (rv < 0 || rows == NULL)
+		return rv;
+	if (*nframes > 0)
+		fe_mfcc_to_float(fe, rows, (float32 **)rows, *nframes);
+	*block = rows;
+	*cep = (float32 *)rows[0];
+	return rv;
+}
+
+static int fe_go_end_utt(fe_t *fe, float32 *cep, int32 *nframes) {
+	int rv = fe_end_utt(fe, (mfcc_t *)cep, nframes);
+	if (rv >= 0 && *nframes > 0) {
+		mfcc_t *row = (mfcc_t *)cep;
+		fe_mfcc_to_float(fe, &row, &cep, 1);
+	}
+	return rv;
+}
+
+static int fe_go_logspec_to_mfcc(fe_t *fe, const float32 *spec, float32 *cep) {
+	return fe_logspec_to_mfcc(fe, (const mfcc_t *)spec, (mfcc_t *)cep);
+}
+
+static int fe_go_logspec_dct2(fe_t *fe, const float32 *spec, float32 *cep) {
+	return fe_logspec_dct2(fe, (const mfcc_t *)spec, (mfcc_t *)cep);
+}
+
+static int fe_go_mfcc_dct3(fe_t *fe, const float32 *cep, float32 *spec) {
+	return fe_mfcc_dct3(fe, (const mfcc_t *)cep, (mfcc_t *)spec);
+}
+*/
+import "C"
+import "unsafe"
+
+// Functions of the front-end declared in sphinxbase/fe.h. The frames are converted
+// to float32 with fe_mfcc_to_float, so they are the same with fixed point builds.
+
+// FeInitAutoR creates a front-end from the configuration, claiming the ownership of config.
+func FeInitAutoR(config *CommandLn) *Fe {
+	return (*Fe)(unsafe.Pointer(C.fe_init_auto_r((*C.cmd_ln_t)(unsafe.Pointer(config)))))
+}
+
+// FeGetConfig returns the configuration of the front-end, it is not retained.
+func FeGetConfig(fe *Fe) *CommandLn {
+	return (*CommandLn)(unsafe.Pointer(C.fe_get_config((*C.fe_t)(unsafe.Pointer(fe)))))
+}
+
+// FeRetain retains the front-end.
+func FeRetain(fe *Fe) *Fe {
+	return (*Fe)(unsafe.Pointer(C.fe_retain((*C.fe_t)(unsafe.Pointer(fe)))))
+}
+
+// FeFree releases the front-end, returns the new reference count.
+func FeFree(fe *Fe) int32 {
+	return int32(C.fe_free((*C.fe_t)(unsafe.Pointer(fe))))
+}
+
+// FeStartStream starts processing of the stream, resetting the frame counter.
+func FeStartStream(fe *Fe) {
+	C.fe_start_stream((*C.fe_t)(unsafe.Pointer(fe)))
+}
+
+// FeStartUtt starts processing of an utterance.
+func FeStartUtt(fe *Fe) int32 {
+	return int32(C.fe_start_utt((*C.fe_t)(unsafe.Pointer(fe))))
+}
+
+// FeGetOutputSize returns the number of values in a frame.
+func FeGetOutputSize(fe *Fe) int32 {
+	return int32(C.fe_get_output_size((*C.fe_t)(unsafe.Pointer(fe))))
+}
+
+// FeGetInputSize returns the number of samples between the frames and in a frame.
+func FeGetInputSize(fe *Fe) (frameShift, frameSize int32) {
+	var shift, size C.int
+	C.fe_get_input_size((*C.fe_t)(unsafe.Pointer(fe)), &shift, &size)
+	return int32(shift), int32(size)
+}
+
+// FeGetVadState returns 1 if the last processed frame is speech, 0 if silence.
+func FeGetVadState(fe *Fe) uint8 {
+	return uint8(C.fe_get_vad_state((*C.fe_t)(unsafe.Pointer(fe))))
+}
+
+// FeProcessFrames processes the samples into at most len(cep)/FeGetOutputSize frames stored
+// one after another in cep. If cep is nil, returns the number of frames the samples would
+// produce without processing them. Returns the number of samples left unprocessed.
+func FeProcessFrames(fe *Fe, spch []int16, cep []float32) (left int, nframes int32, frameIdx int32, ret int32) {
+	var pspch *C.int16
+	if len(spch) > 0 {
+		pspch = (*C.int16)(unsafe.Pointer(&spch[0]))
+	}
+	var pcep *C.float32
+	nsamps := C.size_t(len(spch))
+	if len(cep) > 0 {
+		pcep = (*C.float32)(unsafe.Pointer(&cep[0]))
+		nframes = int32(len(cep)) / FeGetOutputSize(fe)
+	} else {
+		nframes = int32(len(spch))
+	}
+	var cnframes, cidx C.int32 = C.int32(nframes), 0
+	ret = int32(C.fe_go_process_frames((*C.fe_t)(unsafe.Pointer(fe)), pspch, &nsamps, pcep, &cnframes, &cidx))
+	return int(nsamps), int32(cnframes), int32(cidx), ret
+}
+
+// FeProcessUtt processes all the samples, returns the frames stored one after another.
+func FeProcessUtt(fe *Fe, spch []int16) (cep []float32, nframes int32, ret int32) {
+	var pspch *C.int16
+	if len(spch) > 0 {
+		pspch = (*C.int16)(unsafe.Pointer(&spch[0]))
+	}
+	var block unsafe.Pointer
+	var pcep *C.float32
+	var cnframes C.int32
+	ret = int32(C.fe_go_process_utt((*C.fe_t)(unsafe.Pointer(fe)), pspch, C.size_t(len(spch)), &block, &pcep, &cnframes))
+	if block == nil {
+		return nil, 0, ret
+	}
+	defer C.fe_free_2d(block)
+	nframes = int32(cnframes)
+	n := int(nframes) * int(FeGetOutputSize(fe))
+	cep = make([]float32, n)
+	if n > 0 {
+		copy(cep, (*[1 << 28]float32)(unsafe.Pointer(pcep))[:n:n])
+	}
+	return cep, nframes, ret
+}
+
+// FeEndUtt finishes the utterance, storing the residual frame if any in cep,
+// which must hold FeGetOutputSize values. Returns the number of frames stored.
+func FeEndUtt(fe *Fe, cep []float32) (nframes int32, ret int32) {
+	var cnframes C.int32
+	ret = int32(C.fe_go_end_utt((*C.fe_t)(unsafe.Pointer(fe)), (*C.float32)(unsafe.Pointer(&cep[0])), &cnframes))
+	return int32(cnframes), ret
+}
+
+// FeLogspecToMfcc converts a frame of log spectrum to MFCC with the DCT variant of the front-end.
+func FeLogspecToMfcc(fe *Fe, spec, cep []float32) int32 {
+	return int32(C.fe_go_logspec_to_mfcc((*C.fe_t)(unsafe.Pointer(fe)),
+		(*C.float32)(unsafe.Pointer(&spec[0])), (*C.float32)(unsafe.Pointer(&cep[0]))))
+}
+
+// FeLogspecDct2 converts a frame of log spectrum to MFCC with the unitary DCT-II.
+func FeLogspecDct2(fe *Fe, spec, cep []float32) int32 {
+	return int32(C.fe_go_logspec_dct2((*C.fe_t)(unsafe.Pointer(fe)),
+		(*C.float32)(unsafe.Pointer(&spec[0])), (*C.float32)(unsafe.Pointer(&cep[0]))))
+}
+
+// FeMfccDct3 converts a frame of MFCC to log spectrum with the unitary DCT-III.
+func FeMfccDct3(fe *Fe, cep, spec []float32) int32 {
+	return int32(C.fe_go_mfcc_dct3((*C.fe_t)(unsafe.Pointer(fe)),
+		(*C.float32)(unsafe.Pointer(&cep[0])), (*C.float32)(unsafe.Pointer(&spec[0]))))
+}
@@ -0,0 +1,286 @@
+package pocketsphinx
+
+/*
+#cgo pkg-config: pocketsphinx
+#include "pocketsphinx.h"
+#include <sphinxbase/feat.h>
+#include <sphinxbase/cmn.h>
+#include <sphinxbase/agc.h>
+#include <stdlib.h>
+#include <string.h>
+#include "cgo_helpers.h"
+
+// feat_go_init creates the feature computation as acmod_init_feat() does.
+static feat_t *feat_go_init(cmd_ln_t *config) {
+	feat_t *fcb;
+	const char *lda, *svspec, *cmninit, *agc;
+
+	agc = cmd_ln_str_r(config, "-agc");
+	fcb = feat_init(cmd_ln_str_r(config, "-feat"),
+	                cmn_type_from_str(cmd_ln_str_r(config, "-cmn")),
+	                cmd_ln_int_r(config, "-varnorm"),
+	                agc_type_from_str(agc),
+	                0, cmd_ln_int_r(config, "-ceplen"));
+	if (fcb == NULL)
+		return NULL;
+	if ((lda = cmd_ln_str_r(config, "-lda")) != NULL) {
+		if (feat_read_lda(fcb, lda, cmd_ln_int_r(config, "-ldadim")) < 0) {
+			feat_free(fcb);
+			return NULL;
+		}
+	}
+	if ((svspec = cmd_ln_str_r(config, "-svspec")) != NULL) {
+		int32 **subvecs = parse_subvecs(svspec);
+		if (subvecs == NULL || feat_set_subvecs(fcb, subvecs) < 0) {
+			feat_free(fcb);
+			return NULL;
+		}
+	}
+	if (fcb->agc_struct && agc != NULL && strcmp(agc, "none") != 0)
+		agc_set_threshold(fcb->agc_struct, (float32)cmd_ln_float_r(config, "-agcthresh"));
+	if (fcb->cmn_struct && (cmninit = cmd_ln_str_r(config, "-cmninit")) != NULL) {
+		char *vals = strdup(cmninit), *c = vals, *cc;
+		int32 n = 0;
+		while (n < fcb->cmn_struct->veclen && c != NULL && *c != '\0') {
+			if ((cc = strchr(c, ',')) != NULL)
+				*cc++ = '\0';
+			fcb->cmn_struct->cmn_mean[n++] = FLOAT2MFCC(atof(c));
+			c = cc;
+		}
+		free(vals);
+	}
+	return fcb;
+}
+
+static void feat_go_set_agc(feat_t *fcb, int32 agc) {
+	if (fcb->agc_struct == NULL && agc != AGC_NONE)
+		fcb->agc_struct = agc_init();
+	fcb->agc = (agc_type_t)agc;
+}
+
+// cmn_go_live_get stores the live cepstral mean converted to float32 in vec.
+static void cmn_go_live_get(cmn_t *cmn, float32 *vec) {
+	mfcc_t *mean = malloc(cmn->veclen * sizeof(*mean));
+	int32 i;
+
+	cmn_live_get(cmn, mean);
+	for (i = 0; i < cmn->veclen; i++)
+		vec[i] = MFCC2FLOAT(mean[i]);
+	free(mean);
+}
+
+// cmn_go_live_set sets the live cepstral mean from vec converted to mfcc_t.
+static void cmn_go_live_set(cmn_t *cmn, float32 *vec) {
+	mfcc_t *mean = malloc(cmn->veclen * sizeof(*mean));
+	int32 i;
+
+	for (i = 0; i < cmn->veclen; i++)
+		mean[i] = FLOAT2MFCC(vec[i]);
+	cmn_live_set(cmn, mean);
+	free(mean);
+}
+
+static int32 feat_go_dimension2(feat_t *fcb, int32 i) {
+	return feat_dimension2(fcb, i);
+}
+
+// feat_go_live computes the features of ncep frames of cepstra stored one after another,
+// the features of the frames computed are copied one after another to out. The cepstra
+// are converted to mfcc_t and the features back to float32.
+static int32 feat_go_live(feat_t *fcb, float32 *cep, int32 *inout_ncep,
+                          int32 beginutt, int32 endutt, float32 *out, int32 outlen) {
+	mfcc_t **rows = NULL, *buf = NULL;
+	mfcc_t ***feat;
+	int32 i, nfr, size, ceplen = feat_cepsize(fcb);
+
+	if (*inout_ncep > 0) {
+		rows = malloc(*inout_ncep * sizeof(*rows));
+		buf = malloc(*inout_ncep * ceplen * sizeof(*buf));
+		for (i = 0; i < *inout_ncep * ceplen; i++)
+			buf[i] = FLOAT2MFCC(cep[i]);
+		for (i = 0; i < *inout_ncep; i++)
+			rows[i] = buf + i * ceplen;
+	}
+	feat = feat_array_alloc(fcb, *inout_ncep + feat_window_size(fcb));
+	nfr = feat_s2mfc2feat_live(fcb, rows, inout_ncep, beginutt, endutt, feat);
+	free(rows);
+	free(buf);
+	size = 0;
+	for (i = 0; i < feat_dimension1(fcb); i++)
+		size += feat_dimension2(fcb, i);
+	if (nfr * size > outlen)
+		nfr = outlen / size;
+	for (i = 0; i < nfr * size; i++)
+		out[i] = MFCC2FLOAT(feat[0][0][i]);
+	feat_array_free(feat);
+	return nfr;
+}
+*/
+import "C"
+import "unsafe"
+
+// Functions and accessors of the dynamic feature computation declared in sphinxbase/feat.h.
+// The cepstra and the features are converted between float32 and mfcc_t, so they are the same
+// with fixed point builds.
+
+// FeatInitConfig creates the feature computation configured by the -feat, -ceplen, -cmn,
+// -cmninit, -varnorm, -agc, -agcthresh, -lda, -ldadim and -svspec options, as the decoder does.
+func FeatInitConfig(config *CommandLn) *Feat {
+	return (*Feat)(unsafe.Pointer(C.feat_go_init((*C.cmd_ln_t)(unsafe.Pointer(config)))))
+}
+
+// FeatRetain retains the feature computation.
+func FeatRetain(f *Feat) *Feat {
+	return (*Feat)(unsafe.Pointer(C.feat_retain((*C.feat_t)(unsafe.Pointer(f)))))
+}
+
+// FeatFree releases the feature computation, returns the new reference count.
+func FeatFree(f *Feat) int32 {
+	return int32(C.feat_free((*C.feat_t)(unsafe.Pointer(f))))
+}
+
+// FeatName returns a Go-managed copy of the feature type name.
+func FeatName(f *Feat) string {
+	cf := (*C.feat_t)(unsafe.Pointer(f))
+	if cf.name == nil {
+		return ""
+	}
+	return C.GoString(cf.name)
+}
+
+// FeatCepsize returns the number of values in an input frame.
+func FeatCepsize(f *Feat) int32 {
+	return int32((*C.feat_t)(unsafe.Pointer(f)).cepsize)
+}
+
+// FeatWindowSize returns the number of frames on each side of a frame needed to compute its features.
+func FeatWindowSize(f *Feat) int32 {
+	return int32((*C.feat_t)(unsafe.Pointer(f)).window_size)
+}
+
+// FeatDimension1 returns the number of streams or subvectors in the output.
+func FeatDimension1(f *Feat) int32 {
+	cf := (*C.feat_t)(unsafe.Pointer(f))
+	if cf.n_sv != 0 {
+		return int32(cf.n_sv)
+	}
+	return int32(cf.n_stream)
+}
+
+// FeatDimension2 returns the number of values of the stream or subvector i in the output.
+func FeatDimension2(f *Feat, i int32) int32 {
+	return int32(C.feat_go_dimension2((*C.feat_t)(unsafe.Pointer(f)), C.int32(i)))
+}
+
+// FeatNLda returns the number of linear transforms applied to the features.
+func FeatNLda(f *Feat) int32 {
+	cf := (*C.feat_t)(unsafe.Pointer(f))
+	if cf.lda == nil {
+		return 0
+	}
+	return int32(cf.n_lda)
+}
+
+// FeatReadLda adds a linear transform of the features read from a file, dim is the output
+// dimension or 0 to use the entire matrix.
+func FeatReadLda(f *Feat, ldafile string, dim int32) int32 {
+	cfile := C.CString(ldafile)
+	defer C.free(unsafe.Pointer(cfile))
+	return int32(C.feat_read_lda((*C.feat_t)(unsafe.Pointer(f)), cfile, C.int32(dim)))
+}
+
+// FeatS2mfc2featLive computes the features of the cepstra stored one after another in cep, the
+// features of the frames computed are stored one after another in out, which must hold
+// (len(cep)/FeatCepsize + FeatWindowSize) frames. Returns the number of input frames consumed
+// and of the frames computed.
+func FeatS2mfc2featLive(f *Feat, cep []float32, beginUtt, endUtt bool, out []float32) (consumed, nframes int32) {
+	var pcep, pout *C.float32
+	if len(cep) > 0 {
+		pcep = (*C.float32)(unsafe.Pointer(&cep[0]))
+	}
+	if len(out) > 0 {
+		pout = (*C.float32)(unsafe.Pointer(&out[0]))
+	}
+	ncep := C.int32(int32(len(cep)) / FeatCepsize(f))
+	var begin, end C.int32
+	if beginUtt {
+		begin = 1
+	}
+	if endUtt {
+		end = 1
+	}
+	nfr := C.feat_go_live((*C.feat_t)(unsafe.Pointer(f)), pcep, &ncep, begin, end, pout, C.int32(len(out)))
+	return int32(ncep), int32(nfr)
+}
+
+// FeatUpdateStats updates the live normalization statistics at the end of an utterance.
+func FeatUpdateStats(f *Feat) {
+	C.feat_update_stats((*C.feat_t)(unsafe.Pointer(f)))
+}
+
+// FeatCmnVeclen returns the number of values of the cepstral mean, 0 if CMN is disabled.
+func FeatCmnVeclen(f *Feat) int32 {
+	cf := (*C.feat_t)(unsafe.Pointer(f))
+	if cf.cmn_struct == nil {
+		return 0
+	}
+	return int32(cf.cmn_struct.veclen)
+}
+
+// FeatCmnLiveGet stores the live cepstral mean in vec, which must hold FeatCmnVeclen values.
+func FeatCmnLiveGet(f *Feat, vec []float32) {
+	C.cmn_go_live_get((*C.feat_t)(unsafe.Pointer(f)).cmn_struct, (*C.float32)(unsafe.Pointer(&vec[0])))
+}
+
+// FeatCmnLiveSet sets the live cepstral mean from vec, which must hold FeatCmnVeclen values.
+func FeatCmnLiveSet(f *Feat, vec []float32) {
+	C.cmn_go_live_set((*C.feat_t)(unsafe.Pointer(f)).cmn_struct, (*C.float32)(unsafe.Pointer(&vec[0])))
+}
+
+// FeatCmnLiveUpdate updates the live cepstral mean from the frames observed so far.
+func FeatCmnLiveUpdate(f *Feat) {
+	C.cmn_live_update((*C.feat_t)(unsafe.Pointer(f)).cmn_struct)
+}
+
+// AGC modes of agc_type_t declared in sphinxbase/agc.h.
+const (
+	AgcNone  = int32(C.AGC_NONE)
+	AgcMax   = int32(C.AGC_MAX)
+	AgcEmax  = int32(C.AGC_EMAX)
+	AgcNoise = int32(C.AGC_NOISE)
+)
+
+// FeatAgcType returns the AGC mode of the feature computation.
+func FeatAgcType(f *Feat) int32 {
+	return int32((*C.feat_t)(unsafe.Pointer(f)).agc)
+}
+
+// FeatSetAgcType sets the AGC mode of the feature computation, creating the AGC state if needed.
+func FeatSetAgcType(f *Feat, agc int32) {
+	C.feat_go_set_agc((*C.feat_t)(unsafe.Pointer(f)), C.int32(agc))
+}
+
+// FeatHasAgc reports whether the feature computation has the AGC state.
+func FeatHasAgc(f *Feat) bool {
+	return (*C.feat_t)(unsafe.Pointer(f)).agc_struct != nil
+}
+
+// FeatAgcEmaxGet returns the current estimate of the maximum of C0 for AGC_EMAX.
+func FeatAgcEmaxGet(f *Feat) float32 {
+	return float32(C.agc_emax_get((*C.feat_t)(unsafe.Pointer(f)).agc_struct))
+}
+
+// FeatAgcEmaxSet sets the current estimate of the maximum of C0 for AGC_EMAX.
+func FeatAgcEmaxSet(f *Feat, m float32) {
+	C.agc_emax_set((*C.feat_t)(unsafe.Pointer(f)).agc_struct, C.float32(m))
+}
+
+// FeatAgcGetThreshold returns the noise threshold for AGC_NOISE.
+func FeatAgcGetThreshold(f *Feat) float32 {
+	return float32(C.agc_get_threshold((*C.feat_t)(unsafe.Pointer(f)).agc_struct))
+}
+
+// FeatAgcSetThreshold sets the noise threshold for AGC_NOISE.
+func FeatAgcSetThreshold(f *Feat, threshold float32) {
+	C.agc_set_threshold((*C.feat_t)(unsafe.Pointer(f)).agc_struct, C.float32(threshold))
+}
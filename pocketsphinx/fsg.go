@@ -0,0 +1,90 @@
+package pocketsphinx
+
+/*
+#cgo pkg-config: pocketsphinx
+#include "pocketsphinx.h"
+#include <sphinxbase/fsg_model.h>
+#include "cgo_helpers.h"
+
+static int fsg_go_is_filler(fsg_model_t *fsg, int32 wid) {
+	return fsg_model_is_filler(fsg, wid) ? 1 : 0;
+}
+
+static int fsg_go_is_alt(fsg_model_t *fsg, int32 wid) {
+	return fsg_model_is_alt(fsg, wid) ? 1 : 0;
+}
+*/
+import "C"
+import "unsafe"
+
+// Accessors for the fields of fsg_model_t exposed by macros in sphinxbase/fsg_model.h.
+
+// FsgModelName returns a Go-managed copy of the name of the grammar.
+func FsgModelName(fsg *FsgModel) string {
+	cfsg := (*C.fsg_model_t)(unsafe.Pointer(fsg))
+	if cfsg.name == nil {
+		return ""
+	}
+	return C.GoString(cfsg.name)
+}
+
+// FsgModelNState returns the number of states in the grammar.
+func FsgModelNState(fsg *FsgModel) int32 {
+	return int32((*C.fsg_model_t)(unsafe.Pointer(fsg)).n_state)
+}
+
+// FsgModelStartState returns the start state of the grammar.
+func FsgModelStartState(fsg *FsgModel) int32 {
+	return int32((*C.fsg_model_t)(unsafe.Pointer(fsg)).start_state)
+}
+
+// FsgModelSetStartState sets the start state of the grammar.
+func FsgModelSetStartState(fsg *FsgModel, state int32) {
+	(*C.fsg_model_t)(unsafe.Pointer(fsg)).start_state = C.int32(state)
+}
+
+// FsgModelFinalState returns the final state of the grammar.
+func FsgModelFinalState(fsg *FsgModel) int32 {
+	return int32((*C.fsg_model_t)(unsafe.Pointer(fsg)).final_state)
+}
+
+// FsgModelSetFinalState sets the final state of the grammar.
+func FsgModelSetFinalState(fsg *FsgModel, state int32) {
+	(*C.fsg_model_t)(unsafe.Pointer(fsg)).final_state = C.int32(state)
+}
+
+// FsgModelNWord returns the number of words in the vocabulary of the grammar.
+func FsgModelNWord(fsg *FsgModel) int32 {
+	return int32((*C.fsg_model_t)(unsafe.Pointer(fsg)).n_word)
+}
+
+// FsgModelWordStr returns a Go-managed copy of the word with the given ID,
+// or empty string if the ID is out of range.
+func FsgModelWordStr(fsg *FsgModel, wid int32) string {
+	cfsg := (*C.fsg_model_t)(unsafe.Pointer(fsg))
+	if wid < 0 || wid >= int32(cfsg.n_word) {
+		return ""
+	}
+	vocab := (*[1 << 28]*C.char)(unsafe.Pointer(cfsg.vocab))
+	return C.GoString(vocab[wid])
+}
+
+// FsgModelLw returns the language weight applied to the transition probabilities.
+func FsgModelLw(fsg *FsgModel) float32 {
+	return float32((*C.fsg_model_t)(unsafe.Pointer(fsg)).lw)
+}
+
+// FsgModelLogmath returns the log-math object of the grammar, it is not retained.
+func FsgModelLogmath(fsg *FsgModel) *Logmath {
+	return (*Logmath)(unsafe.Pointer((*C.fsg_model_t)(unsafe.Pointer(fsg)).lmath))
+}
+
+// FsgModelIsFiller reports whether the word has been added as silence or filler.
+func FsgModelIsFiller(fsg *FsgModel, wid int32) bool {
+	return C.fsg_go_is_filler((*C.fsg_model_t)(unsafe.Pointer(fsg)), C.int32(wid)) != 0
+}
+
+// FsgModelIsAlt reports whether the word has been added as a pronunciation alternative.
+func FsgModelIsAlt(fsg *FsgModel, wid int32) bool {
+	return C.fsg_go_is_alt((*C.fsg_model_t)(unsafe.Pointer(fsg)), C.int32(wid)) != 0
+}
@@ -0,0 +1,28 @@
+package pocketsphinx
+
+/*
+#cgo pkg-config: pocketsphinx
+#include "pocketsphinx.h"
+#include "cgo_helpers.h"
+
+static jsgf_rule_t *jsgf_go_iter_rule(jsgf_rule_iter_t *itor) {
+	return jsgf_rule_iter_rule(itor);
+}
+
+static jsgf_rule_iter_t *jsgf_go_iter_next(jsgf_rule_iter_t *itor) {
+	return jsgf_rule_iter_next(itor);
+}
+*/
+import "C"
+import "unsafe"
+
+// JSGFRules returns all rules of the grammar, including the ones of imported grammars,
+// iterating with the rule iterator macros declared in sphinxbase/jsgf.h.
+func JSGFRules(grammar *JSGF) []*JSGFRule {
+	cgrammar := (*C.jsgf_t)(unsafe.Pointer(grammar))
+	var rules []*JSGFRule
+	for itor := C.jsgf_rule_iter(cgrammar); itor != nil; itor = C.jsgf_go_iter_next(itor) {
+		rules = append(rules, (*JSGFRule)(unsafe.Pointer(C.jsgf_go_iter_rule(itor))))
+	}
+	return rules
+}
@@ -34,3 +34,29 @@ func Args() []Arg {
 	packSArg(__v, __ret)
 	return __v
 }
+
+// SearchIterVal function as declared in pocketsphinx/ps_search.h:143
+//
+// Returns a Go-managed copy of the search name, since the underlying
+// string is owned by the decoder.
+func SearchIterVal(itor *SearchIter) string {
+	citor := (*C.ps_search_iter_t)(unsafe.Pointer(itor))
+	__ret := C.ps_search_iter_val(citor)
+	if __ret == nil {
+		return ""
+	}
+	return C.GoString(__ret)
+}
+
+// LookupWordPhones is a variant of LookupWord that returns a Go-managed copy
+// of the phone string, and false if word is not present in the dictionary.
+func LookupWordPhones(ps *Decoder, word string) (string, bool) {
+	cps := (*C.ps_decoder_t)(unsafe.Pointer(ps))
+	cword, _ := unpackPCharString(word)
+	__ret := C.ps_lookup_word(cps, cword)
+	if __ret == nil {
+		return "", false
+	}
+	defer C.free(unsafe.Pointer(__ret))
+	return C.GoString(__ret), true
+}
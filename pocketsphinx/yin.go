@@ -0,0 +1,48 @@
+package pocketsphinx
+
+/*
+#cgo pkg-config: pocketsphinx
+#include "pocketsphinx.h"
+#include <sphinxbase/yin.h>
+#include "cgo_helpers.h"
+*/
+import "C"
+import "unsafe"
+
+// Functions of the YIN pitch estimator declared in sphinxbase/yin.h.
+
+// Yin as declared in sphinxbase/yin.h
+type Yin C.yin_t
+
+// YinInit creates a moving-window pitch estimator for frames of frameSize samples.
+func YinInit(frameSize int32, searchThreshold, searchRange float32, smoothWindow int32) *Yin {
+	return (*Yin)(unsafe.Pointer(C.yin_init(C.int(frameSize), C.float(searchThreshold), C.float(searchRange), C.int(smoothWindow))))
+}
+
+// YinFree frees the pitch estimator.
+func YinFree(pe *Yin) {
+	C.yin_free((*C.yin_t)(unsafe.Pointer(pe)))
+}
+
+// YinStart starts processing of an utterance.
+func YinStart(pe *Yin) {
+	C.yin_start((*C.yin_t)(unsafe.Pointer(pe)))
+}
+
+// YinEnd marks the end of an utterance, so the estimates of the last frames can be read.
+func YinEnd(pe *Yin) {
+	C.yin_end((*C.yin_t)(unsafe.Pointer(pe)))
+}
+
+// YinWrite feeds a frame of frameSize samples to the pitch estimator.
+func YinWrite(pe *Yin, frame []int16) {
+	C.yin_write((*C.yin_t)(unsafe.Pointer(pe)), (*C.int16)(unsafe.Pointer(&frame[0])))
+}
+
+// YinRead reads the next estimate of the period in samples, and the minimum normalized
+// difference in Q15 format. Returns false if there is not enough data for an estimate.
+func YinRead(pe *Yin) (period, bestDiff uint16, ok bool) {
+	var cperiod, cdiff C.uint16
+	ret := C.yin_read((*C.yin_t)(unsafe.Pointer(pe)), &cperiod, &cdiff)
+	return uint16(cperiod), uint16(cdiff), ret != 0
+}
@@ -0,0 +1,135 @@
+package sphinx
+
+import (
+	"fmt"
+
+	"github.com/xlab/pocketsphinx-go/pocketsphinx"
+)
+
+// AGCMode is the automatic gain control applied to c0, the energy coefficient of the cepstra.
+//
+// AGCMax and AGCNoise need whole utterances. In the streaming mode, i.e. unless the utterance
+// is processed in a single call, as FeatureComputer.ProcessUtt() does or Decoder.ProcessRaw()
+// and Decoder.ProcessCep() do with fullUtterance set, sphinxbase applies AGCEMax instead.
+type AGCMode string
+
+const (
+	// AGCNone disables the gain control.
+	AGCNone AGCMode = "none"
+	// AGCMax normalizes c0 by its maximum in the utterance, it needs whole utterances.
+	AGCMax AGCMode = "max"
+	// AGCEMax normalizes c0 by its maximum estimated from the previous utterances.
+	AGCEMax AGCMode = "emax"
+	// AGCNoise sets c0 below the noise threshold to the threshold, it needs whole utterances.
+	AGCNoise AGCMode = "noise"
+)
+
+var agcModes = []struct {
+	mode AGCMode
+	typ  int32
+}{
+	{AGCNone, pocketsphinx.AgcNone},
+	{AGCMax, pocketsphinx.AgcMax},
+	{AGCEMax, pocketsphinx.AgcEmax},
+	{AGCNoise, pocketsphinx.AgcNoise},
+}
+
+// AGC gets the gain control mode and threshold set by the -agc and -agcthresh options.
+func (c *Config) AGC() (mode AGCMode, threshold float32) {
+	s, _ := c.GetString("agc")
+	t, _ := c.GetFloat("agcthresh")
+	return AGCMode(s), float32(t)
+}
+
+// AGCState is the state of the automatic gain control of a stream.
+type AGCState struct {
+	Mode AGCMode
+	// Max is the estimate of the maximum of c0 used by AGCEMax, it is updated
+	// at the end of every utterance.
+	Max float32
+	// Threshold is the noise threshold used by AGCNoise, it has no effect in the streaming mode.
+	Threshold float32
+}
+
+// AGC gets the state of the gain control.
+func (fc *FeatureComputer) AGC() AGCState {
+	state := AGCState{
+		Mode: AGCNone,
+	}
+	typ := pocketsphinx.FeatAgcType(fc.f)
+	for _, m := range agcModes {
+		if m.typ == typ {
+			state.Mode = m.mode
+		}
+	}
+	if pocketsphinx.FeatHasAgc(fc.f) {
+		state.Max = pocketsphinx.FeatAgcEmaxGet(fc.f)
+		state.Threshold = pocketsphinx.FeatAgcGetThreshold(fc.f)
+	}
+	return state
+}
+
+// SetAGC sets the gain control mode, the estimate of the maximum and the threshold are kept.
+// In the streaming mode AGCMax and AGCNoise act as AGCEMax, see AGCMode.
+func (fc *FeatureComputer) SetAGC(mode AGCMode) error {
+	for _, m := range agcModes {
+		if m.mode == mode {
+			pocketsphinx.FeatSetAgcType(fc.f, m.typ)
+			return nil
+		}
+	}
+	return &Error{Op: "SetAGC", Arg: string(mode), Err: fmt.Errorf("%w: unknown AGC mode", ErrInvalidValue)}
+}
+
+// SetAGCMax sets the estimate of the maximum of c0 used by AGCEMax, e.g. one saved
+// from an earlier stream of the same room.
+func (fc *FeatureComputer) SetAGCMax(max float32) error {
+	if !pocketsphinx.FeatHasAgc(fc.f) {
+		return &Error{Op: "SetAGCMax", Err: fmt.Errorf("%w: AGC is disabled", ErrFailed)}
+	}
+	pocketsphinx.FeatAgcEmaxSet(fc.f, max)
+	return nil
+}
+
+// SetAGCThreshold sets the noise threshold used by AGCNoise, it only applies to whole
+// utterances, see AGCMode.
+func (fc *FeatureComputer) SetAGCThreshold(threshold float32) error {
+	if !pocketsphinx.FeatHasAgc(fc.f) {
+		return &Error{Op: "SetAGCThreshold", Err: fmt.Errorf("%w: AGC is disabled", ErrFailed)}
+	}
+	pocketsphinx.FeatAgcSetThreshold(fc.f, threshold)
+	return nil
+}
+
+// AGC gets the state of the gain control of the decoder.
+func (d *Decoder) AGC() AGCState {
+	return d.FeatureComputer().AGC()
+}
+
+// SetAGC sets the gain control mode of the decoder without reinitializing it, until the
+// decoder is reconfigured. It cannot be set during an utterance. When decoding audio or
+// cepstra in blocks, AGCMax and AGCNoise act as AGCEMax, see AGCMode.
+func (d *Decoder) SetAGC(mode AGCMode) error {
+	if d.uttStarted {
+		return &Error{Op: "SetAGC", Err: ErrAlreadyStarted}
+	}
+	return d.FeatureComputer().SetAGC(mode)
+}
+
+// SetAGCMax sets the estimate of the maximum of c0 used by AGCEMax.
+// It cannot be set during an utterance.
+func (d *Decoder) SetAGCMax(max float32) error {
+	if d.uttStarted {
+		return &Error{Op: "SetAGCMax", Err: ErrAlreadyStarted}
+	}
+	return d.FeatureComputer().SetAGCMax(max)
+}
+
+// SetAGCThreshold sets the noise threshold used by AGCNoise, it only applies to whole
+// utterances, see AGCMode. It cannot be set during an utterance.
+func (d *Decoder) SetAGCThreshold(threshold float32) error {
+	if d.uttStarted {
+		return &Error{Op: "SetAGCThreshold", Err: ErrAlreadyStarted}
+	}
+	return d.FeatureComputer().SetAGCThreshold(threshold)
+}
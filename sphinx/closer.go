@@ -0,0 +1,63 @@
+package sphinx
+
+import (
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// Ownership of the C objects.
+//
+// Every wrapper that owns a reference to a C object implements io.Closer, closing it
+// releases the reference. Close is idempotent, so it is safe to close an object twice.
+// Objects that are garbage collected without being closed are reported as leaks
+// (see SetLeakFunc) and released by their finalizers.
+//
+// Objects returned by getters like Decoder.LogMath() or Decoder.WordLattice() are borrowed
+// from their parent, closing them is a no-op. Use their Retain() method to get an owned
+// reference that outlives the parent.
+
+var (
+	_ io.Closer = (*Config)(nil)
+	_ io.Closer = (*Decoder)(nil)
+	_ io.Closer = (*Lattice)(nil)
+	_ io.Closer = (*LogMath)(nil)
+	_ io.Closer = (*MLLR)(nil)
+	_ io.Closer = (*NGramModel)(nil)
+	_ io.Closer = (*NGramOptions)(nil)
+	_ io.Closer = (*FSG)(nil)
+	_ io.Closer = (*JSGF)(nil)
+	_ io.Closer = (*FrontEnd)(nil)
+	_ io.Closer = (*FeatureComputer)(nil)
+	_ io.Closer = (*PitchTracker)(nil)
+	_ io.Closer = (*DecoderPool)(nil)
+)
+
+var leakFunc = struct {
+	sync.Mutex
+	fn func(typ string)
+}{
+	fn: func(typ string) {
+		slog.Default().Warn("sphinx: object has been garbage collected without Close", "type", typ)
+	},
+}
+
+// SetLeakFunc sets the function called when an owned object is garbage collected without
+// being closed, typ is the name of its type, e.g. "Decoder". The object is released after fn
+// returns. By default leaks are logged as warnings to slog.Default(), pass nil to disable it.
+//
+// Note that fn is invoked from the finalizer goroutine.
+func SetLeakFunc(fn func(typ string)) {
+	leakFunc.Lock()
+	leakFunc.fn = fn
+	leakFunc.Unlock()
+}
+
+func reportLeak(typ string) {
+	leakFunc.Lock()
+	fn := leakFunc.fn
+	leakFunc.Unlock()
+	if fn != nil {
+		fn(typ)
+	}
+}
@@ -0,0 +1,188 @@
+package sphinx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/xlab/pocketsphinx-go/pocketsphinx"
+)
+
+// Cepstral mean normalization (CMN) subtracts the mean of the cepstra from every frame, to remove
+// the effect of the channel and the speaker. With live CMN (-cmn live) the mean is estimated
+// from the utterances processed so far, starting from -cmninit, so the first utterances of a
+// stream are normalized poorly. Seeding the mean saved from an earlier stream of the same
+// speaker or channel avoids that, see CMNStore.
+
+// CMN gets the current live cepstral mean.
+func (fc *FeatureComputer) CMN() ([]float32, error) {
+	n := pocketsphinx.FeatCmnVeclen(fc.f)
+	if n == 0 {
+		return nil, &Error{Op: "CMN", Err: fmt.Errorf("%w: CMN is disabled", ErrFailed)}
+	}
+	vec := make([]float32, n)
+	pocketsphinx.FeatCmnLiveGet(fc.f, vec)
+	return vec, nil
+}
+
+// SetCMN sets the live cepstral mean, it must have one value per cepstral coefficient.
+func (fc *FeatureComputer) SetCMN(vec []float32) error {
+	n := int(pocketsphinx.FeatCmnVeclen(fc.f))
+	if n == 0 {
+		return &Error{Op: "SetCMN", Err: fmt.Errorf("%w: CMN is disabled", ErrFailed)}
+	}
+	if len(vec) != n {
+		return &Error{Op: "SetCMN", Err: fmt.Errorf("%w: %d values, expected %d", ErrBadFrame, len(vec), n)}
+	}
+	pocketsphinx.FeatCmnLiveSet(fc.f, vec)
+	return nil
+}
+
+// UpdateCMN updates the live cepstral mean from the frames processed so far,
+// it is done by FeatureComputer.EndUtt() as well.
+func (fc *FeatureComputer) UpdateCMN() {
+	if pocketsphinx.FeatCmnVeclen(fc.f) > 0 {
+		pocketsphinx.FeatCmnLiveUpdate(fc.f)
+	}
+}
+
+// CMN gets the current live cepstral mean of the decoder, it is updated at the end of every utterance.
+func (d *Decoder) CMN() ([]float32, error) {
+	return d.FeatureComputer().CMN()
+}
+
+// SetCMN sets the live cepstral mean of the decoder, it must have one value per cepstral
+// coefficient. It cannot be set during an utterance.
+func (d *Decoder) SetCMN(vec []float32) error {
+	if d.uttStarted {
+		return &Error{Op: "SetCMN", Err: ErrAlreadyStarted}
+	}
+	return d.FeatureComputer().SetCMN(vec)
+}
+
+// CMNStore keeps the cepstral means per speaker or channel, so a returning speaker starts with
+// their own normalization:
+//
+//	store.Restore(dec, callerID)
+//	// decode the utterances of the caller
+//	store.Save(dec, callerID)
+//
+// The store is safe for concurrent use, and can be persisted as JSON.
+type CMNStore struct {
+	mux   sync.RWMutex
+	means map[string][]float32
+}
+
+// NewCMNStore creates an empty store.
+func NewCMNStore() *CMNStore {
+	return &CMNStore{
+		means: make(map[string][]float32),
+	}
+}
+
+// ReadCMNStore reads a store written by CMNStore.WriteTo().
+func ReadCMNStore(r io.Reader) (*CMNStore, error) {
+	s := NewCMNStore()
+	if err := json.NewDecoder(r).Decode(s); err != nil {
+		return nil, &Error{Op: "ReadCMNStore", Err: fmt.Errorf("%w: %v", ErrFailed, err)}
+	}
+	return s, nil
+}
+
+// Get gets a copy of the mean stored for the key.
+func (s *CMNStore) Get(key string) ([]float32, bool) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	vec, ok := s.means[key]
+	return append([]float32(nil), vec...), ok
+}
+
+// Put stores a copy of the mean for the key.
+func (s *CMNStore) Put(key string, vec []float32) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.means == nil {
+		s.means = make(map[string][]float32)
+	}
+	s.means[key] = append([]float32(nil), vec...)
+}
+
+// Delete removes the mean stored for the key.
+func (s *CMNStore) Delete(key string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.means, key)
+}
+
+// Keys lists the keys of the stored means in sorted order.
+func (s *CMNStore) Keys() []string {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	keys := make([]string, 0, len(s.means))
+	for key := range s.means {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Save stores the current cepstral mean of the decoder for the key.
+func (s *CMNStore) Save(d *Decoder, key string) error {
+	vec, err := d.CMN()
+	if err != nil {
+		return err
+	}
+	s.Put(key, vec)
+	return nil
+}
+
+// Restore sets the cepstral mean of the decoder stored for the key, if any.
+// Reports whether a mean has been found.
+func (s *CMNStore) Restore(d *Decoder, key string) (bool, error) {
+	vec, ok := s.Get(key)
+	if !ok {
+		return false, nil
+	}
+	if err := d.SetCMN(vec); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// MarshalJSON encodes the store as an object mapping the keys to the means.
+func (s *CMNStore) MarshalJSON() ([]byte, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	means := s.means
+	if means == nil {
+		means = make(map[string][]float32)
+	}
+	return json.Marshal(means)
+}
+
+// UnmarshalJSON decodes the store encoded by CMNStore.MarshalJSON(), replacing its contents.
+func (s *CMNStore) UnmarshalJSON(data []byte) error {
+	var means map[string][]float32
+	if err := json.Unmarshal(data, &means); err != nil {
+		return err
+	}
+	if means == nil {
+		means = make(map[string][]float32)
+	}
+	s.mux.Lock()
+	s.means = means
+	s.mux.Unlock()
+	return nil
+}
+
+// WriteTo writes the store as JSON.
+func (s *CMNStore) WriteTo(w io.Writer) (int64, error) {
+	data, err := s.MarshalJSON()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(append(data, '\n'))
+	return int64(n), err
+}
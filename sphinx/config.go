@@ -1,17 +1,24 @@
 package sphinx
 
-import "github.com/xlab/pocketsphinx-go/pocketsphinx"
+import (
+	"runtime"
 
+	"github.com/xlab/pocketsphinx-go/pocketsphinx"
+)
+
+// Config is a set of command-line arguments used to configure the decoder.
 type Config struct {
 	opt       map[String]interface{}
 	evaluated *pocketsphinx.CommandLn
+	// resolvedLn caches the arguments with the acoustic model defaults, see Config.Get().
+	resolvedLn *pocketsphinx.CommandLn
+	// problems keeps the options dropped on evaluation, reported by Config.Validate().
+	problems map[String]error
 }
 
 // NewConfig creates a new command-line argument set based on the provided config options.
 func NewConfig(opts ...Option) *Config {
-	cfg := &Config{
-		opt: make(map[String]interface{}, 32),
-	}
+	cfg := newConfig(nil)
 	for i := range opts {
 		opts[i](cfg)
 	}
@@ -20,40 +27,77 @@ func NewConfig(opts ...Option) *Config {
 
 // NewConfigRetain gets a new config while retaining ownership of a command-line argument set.
 func NewConfigRetain(ln *pocketsphinx.CommandLn) *Config {
-	return &Config{
-		evaluated: pocketsphinx.CommandLnRetain(ln),
-	}
+	return newConfig(pocketsphinx.CommandLnRetain(ln))
 }
 
-// Retain retains ownership of a command-line argument set.
-func (c *Config) Retain() {
-	c.evaluated = pocketsphinx.CommandLnRetain(c.evaluated)
+func newConfig(ln *pocketsphinx.CommandLn) *Config {
+	cfg := &Config{
+		opt:       make(map[String]interface{}, 32),
+		evaluated: ln,
+	}
+	runtime.SetFinalizer(cfg, func(c *Config) {
+		if c.evaluated != nil || c.resolvedLn != nil {
+			reportLeak("Config")
+			c.Close()
+		}
+	})
+	return cfg
 }
 
+// Retain gets a new config sharing the command-line argument set, it must be closed
+// when no longer needed.
+func (c *Config) Retain() *Config {
+	return NewConfigRetain(c.CommandLn())
+}
+
+// CommandLn gets the command-line argument set, evaluating the options set since the last use.
+// The config retains ownership of the argument set.
 func (c *Config) CommandLn() *pocketsphinx.CommandLn {
-	if c.evaluated != nil {
+	if c.evaluated == nil {
+		defn := pocketsphinx.Args()
+		c.evaluated = pocketsphinx.CommandLnParseR(nil, defn, 0, nil, 0)
+	} else if len(c.opt) == 0 {
 		return c.evaluated
 	}
-	defn := pocketsphinx.Args()
-	prevLn := pocketsphinx.CommandLnParseR(nil, defn, 0, nil, 0)
-	c.evaluated = optToCommandLn(prevLn, c.opt)
+	c.checkPending()
+	c.evaluated = optToCommandLn(c.evaluated, c.opt)
+	c.opt = make(map[String]interface{}, len(c.opt))
+	c.dropResolved()
 	return c.evaluated
 }
 
+func (c *Config) dropResolved() {
+	if c.resolvedLn != nil {
+		pocketsphinx.CommandLnFreeR(c.resolvedLn)
+		c.resolvedLn = nil
+	}
+}
+
+// Close releases the evaluated command-line argument set.
+func (c *Config) Close() error {
+	c.Destroy()
+	return nil
+}
+
+// Destroy releases the evaluated command-line argument set, returns true if it has been freed.
+//
+// Deprecated: use Close.
 func (c *Config) Destroy() bool {
+	c.dropResolved()
 	if c.evaluated != nil {
 		ret := pocketsphinx.CommandLnFreeR(c.evaluated)
 		c.evaluated = nil
 		return ret == 0
 	}
-	return true
+	return false
 }
 
 type Option func(c *Config)
 
 // Options for debugging and logging.
 
-// LogFileOption sets file to write log messages in.
+// LogFileOption sets file to write log messages in. Use SetLogHandler or SetLogFunc
+// to route log messages into Go instead.
 func LogFileOption(filename string) Option {
 	return func(c *Config) {
 		c.opt[String("-logfn")] = String(filename)
@@ -555,7 +599,7 @@ func MeansFileOption(filename string) Option {
 }
 
 // VarFileOption sets mixture gaussian variances input file.
-func VarFileOption(filename int) Option {
+func VarFileOption(filename string) Option {
 	return func(c *Config) {
 		c.opt[String("-var")] = String(filename)
 	}
@@ -654,6 +698,26 @@ func LogBaseOption(base float32) Option {
 	}
 }
 
+// Feature normalization options.
+
+// AGCOption sets automatic gain control for c0, see AGCMode.
+//
+// Default: AGCNone
+func AGCOption(mode AGCMode) Option {
+	return func(c *Config) {
+		c.opt[String("-agc")] = String(mode)
+	}
+}
+
+// AGCThresholdOption sets initial threshold for automatic gain control.
+//
+// Default: 2.0
+func AGCThresholdOption(threshold float32) Option {
+	return func(c *Config) {
+		c.opt[String("-agcthresh")] = threshold
+	}
+}
+
 // Misc options.
 
 // SampleRateOption sets sample rate.
@@ -674,7 +738,8 @@ func InputEndianOption(endian string) Option {
 	}
 }
 
-// UserOption sets a user specified option to a custom value.
+// UserOption sets a user specified option to a custom value. The name must include the leading dash,
+// options with unknown names or values of a wrong type are ignored and reported by Config.Validate().
 func UserOption(name string, v interface{}) Option {
 	return func(c *Config) {
 		c.opt[String(name)] = v
@@ -0,0 +1,406 @@
+package sphinx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/xlab/pocketsphinx-go/pocketsphinx"
+)
+
+// argDef describes a single command-line argument of the decoder.
+type argDef struct {
+	Name    string
+	Type    int32
+	Default string
+	Doc     string
+}
+
+// kind gets the value type of the argument without the ArgRequired flag.
+func (a *argDef) kind() int32 {
+	return a.Type &^ pocketsphinx.ArgRequired
+}
+
+var argTable struct {
+	once   sync.Once
+	defs   []argDef
+	byName map[string]*argDef
+	// defaults holds the default values of the arguments that have one.
+	defaults map[string]interface{}
+}
+
+// argDefs loads the argument definitions of the decoder, see pocketsphinx.Args().
+func argDefs() []argDef {
+	argTable.once.Do(func() {
+		args := pocketsphinx.Args()
+		argTable.defs = make([]argDef, 0, len(args))
+		for i := range args {
+			args[i].Deref()
+			argTable.defs = append(argTable.defs, argDef{
+				Name:    pocketsphinx.RawString(args[i].Name).Copy(),
+				Type:    args[i].Type,
+				Default: pocketsphinx.RawString(args[i].Deflt).Copy(),
+				Doc:     pocketsphinx.RawString(args[i].Doc).Copy(),
+			})
+		}
+		argTable.byName = make(map[string]*argDef, len(argTable.defs))
+		for i := range argTable.defs {
+			argTable.byName[argTable.defs[i].Name] = &argTable.defs[i]
+		}
+		ln := pocketsphinx.CommandLnParseR(nil, pocketsphinx.Args(), 0, nil, 0)
+		argTable.defaults = readArgValues(ln, argTable.defs)
+		pocketsphinx.CommandLnFreeR(ln)
+	})
+	return argTable.defs
+}
+
+// lookupArg finds the argument definition by name, the leading dash is optional.
+func lookupArg(name string) (*argDef, bool) {
+	argDefs()
+	def, ok := argTable.byName[argName(name)]
+	return def, ok
+}
+
+func argName(name string) string {
+	if strings.HasPrefix(name, "-") {
+		return name
+	}
+	return "-" + name
+}
+
+// argValue reads the typed value of the argument: int, float64, bool or string.
+// Returns false if the argument has no value.
+func argValue(ln *pocketsphinx.CommandLn, def *argDef) (interface{}, bool) {
+	name := String(def.Name).S()
+	if pocketsphinx.CommandLnExistsR(ln, name) == 0 {
+		return nil, false
+	}
+	switch def.kind() {
+	case pocketsphinx.ArgInteger:
+		return pocketsphinx.CommandLnIntR(ln, name), true
+	case pocketsphinx.ArgFloating:
+		return pocketsphinx.CommandLnFloatR(ln, name), true
+	case pocketsphinx.ArgBoolean:
+		return pocketsphinx.CommandLnIntR(ln, name) != 0, true
+	case pocketsphinx.ArgString:
+		v := pocketsphinx.RawString(pocketsphinx.CommandLnStrR(ln, name)).Copy()
+		if len(v) == 0 {
+			return nil, false
+		}
+		return v, true
+	default:
+		// string lists are not used by the decoder
+		return nil, false
+	}
+}
+
+// argValues reads the values of all arguments that have one.
+func argValues(ln *pocketsphinx.CommandLn) map[string]interface{} {
+	return readArgValues(ln, argDefs())
+}
+
+func readArgValues(ln *pocketsphinx.CommandLn, defs []argDef) map[string]interface{} {
+	values := make(map[string]interface{}, len(defs))
+	for i := range defs {
+		def := &defs[i]
+		if v, ok := argValue(ln, def); ok {
+			values[def.Name] = v
+		}
+	}
+	return values
+}
+
+// parseArgValue converts a value decoded from JSON, YAML or text to the type of the argument.
+func parseArgValue(def *argDef, v interface{}) (interface{}, error) {
+	var s string
+	switch x := v.(type) {
+	case string:
+		s = x
+	case json.Number:
+		s = x.String()
+	case bool:
+		if def.kind() == pocketsphinx.ArgBoolean {
+			return x, nil
+		}
+		s = strconv.FormatBool(x)
+	case int:
+		s = strconv.Itoa(x)
+	case int64:
+		s = strconv.FormatInt(x, 10)
+	case uint64:
+		s = strconv.FormatUint(x, 10)
+	case float64:
+		s = strconv.FormatFloat(x, 'g', -1, 64)
+	default:
+		return nil, fmt.Errorf("%w: unexpected %T", ErrInvalidValue, v)
+	}
+	switch def.kind() {
+	case pocketsphinx.ArgInteger:
+		// base 10 as atoi() of sphinxbase, e.g. 010 is 10
+		n, err := strconv.ParseInt(s, 10, 0)
+		if err != nil {
+			// integers are often written as floats in JSON and YAML
+			f, ferr := strconv.ParseFloat(s, 64)
+			if ferr != nil || f != float64(int(f)) {
+				return nil, fmt.Errorf("%w: %q is not an integer", ErrInvalidValue, s)
+			}
+			n = int64(f)
+		}
+		return int(n), nil
+	case pocketsphinx.ArgFloating:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not a number", ErrInvalidValue, s)
+		}
+		return f, nil
+	case pocketsphinx.ArgBoolean:
+		// the same spellings as accepted by sphinxbase
+		switch strings.ToLower(s) {
+		case "yes", "true", "1", "y", "t":
+			return true, nil
+		case "no", "false", "0", "n", "f":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%w: %q is not a boolean", ErrInvalidValue, s)
+	default:
+		return s, nil
+	}
+}
+
+// LoadConfig reads the configuration from a file of sphinx-style arguments,
+// separated by whitespace, e.g.
+//
+//	-hmm /usr/local/share/pocketsphinx/model/en-us/en-us
+//	-beam 1e-60
+//
+// The options are applied on top of the values read from the file.
+func LoadConfig(filename string, opts ...Option) (*Config, error) {
+	if err := checkFile("LoadConfig", String(filename)); err != nil {
+		return nil, err
+	}
+	mark := markErrors()
+	ln := pocketsphinx.CommandLnParseFileR(nil, pocketsphinx.Args(), String(filename).S(), 1)
+	if ln == nil {
+		return nil, newError(mark, "LoadConfig", filename, ErrFailed)
+	}
+	cfg := newConfig(ln)
+	for i := range opts {
+		opts[i](cfg)
+	}
+	return cfg, nil
+}
+
+// Get gets the value of the option as int, float64, bool or string, the leading
+// dash of the name is optional. Returns false if the option is unknown or has no value.
+//
+// The values are resolved the same way as the decoder does it, so the model files
+// not set explicitly are looked up in the acoustic model directory (-hmm)
+// and the feature parameters are read from its feat.params.
+func (c *Config) Get(name string) (interface{}, bool) {
+	def, ok := lookupArg(name)
+	if !ok {
+		return nil, false
+	}
+	return argValue(c.resolved(), def)
+}
+
+// GetString gets the value of a string option, see Config.Get().
+func (c *Config) GetString(name string) (string, bool) {
+	v, ok := c.Get(name)
+	s, isString := v.(string)
+	return s, ok && isString
+}
+
+// GetInt gets the value of an integer option, see Config.Get().
+func (c *Config) GetInt(name string) (int, bool) {
+	v, ok := c.Get(name)
+	n, isInt := v.(int)
+	return n, ok && isInt
+}
+
+// GetFloat gets the value of a floating point option, see Config.Get().
+func (c *Config) GetFloat(name string) (float64, bool) {
+	v, ok := c.Get(name)
+	f, isFloat := v.(float64)
+	return f, ok && isFloat
+}
+
+// GetBool gets the value of a boolean option, see Config.Get().
+func (c *Config) GetBool(name string) (bool, bool) {
+	v, ok := c.Get(name)
+	b, isBool := v.(bool)
+	return b, ok && isBool
+}
+
+// modelFiles lists the options set from the acoustic model directory
+// when they are not set explicitly, as ps_expand_model_config() does.
+var modelFiles = []struct {
+	name string
+	file string
+}{
+	{"-mdef", "mdef"},
+	{"-mean", "means"},
+	{"-var", "variances"},
+	{"-tmat", "transition_matrices"},
+	{"-mixw", "mixture_weights"},
+	{"-sendump", "sendump"},
+	{"-fdict", "noisedict"},
+	{"-lda", "feature_transform"},
+	{"-featparams", "feat.params"},
+	{"-senmgau", "senmgau"},
+}
+
+// resolved gets a copy of the arguments with the acoustic model defaults applied.
+func (c *Config) resolved() *pocketsphinx.CommandLn {
+	ln := c.CommandLn()
+	if c.resolvedLn != nil {
+		return c.resolvedLn
+	}
+	values := make(map[String]interface{})
+	for name, v := range argValues(ln) {
+		values[String(name)] = v
+	}
+	r := optToCommandLn(pocketsphinx.CommandLnParseR(nil, pocketsphinx.Args(), 0, nil, 0), values)
+	if hmm := pocketsphinx.RawString(pocketsphinx.CommandLnStrR(r, String("-hmm").S())).Copy(); len(hmm) > 0 {
+		for _, f := range modelFiles {
+			name := String(f.name).S()
+			if len(pocketsphinx.CommandLnStrR(r, name)) > 0 {
+				continue
+			}
+			path := filepath.Join(hmm, f.file)
+			if _, err := os.Stat(path); err == nil {
+				pocketsphinx.CommandLnSetStrR(r, name, String(path).S())
+			}
+		}
+	}
+	if featparams := pocketsphinx.RawString(pocketsphinx.CommandLnStrR(r, String("-featparams").S())).Copy(); len(featparams) > 0 {
+		// the model-specific parameters override the configuration, as in acmod_init()
+		pocketsphinx.CommandLnParseFileR(r, pocketsphinx.Args(), String(featparams).S(), 0)
+	}
+	c.resolvedLn = r
+	return r
+}
+
+// Values gets the options that differ from the defaults, keyed by name without the leading dash.
+// The values are int, float64, bool or string.
+func (c *Config) Values() map[string]interface{} {
+	values := make(map[string]interface{})
+	for name, v := range argValues(c.CommandLn()) {
+		if d, ok := argTable.defaults[name]; ok && d == v {
+			continue
+		}
+		values[strings.TrimPrefix(name, "-")] = v
+	}
+	return values
+}
+
+// Set sets the option from a value of int, float64, bool or string type, strings are parsed
+// according to the type of the option. The leading dash of the name is optional.
+func (c *Config) Set(name string, value interface{}) error {
+	def, ok := lookupArg(name)
+	if !ok {
+		return &Error{Op: "Config.Set", Arg: name, Err: ErrUnknownOption}
+	}
+	v, err := parseArgValue(def, value)
+	if err != nil {
+		return &Error{Op: "Config.Set", Arg: name, Err: err}
+	}
+	if c.opt == nil {
+		c.opt = make(map[String]interface{})
+	}
+	c.opt[String(def.Name)] = v
+	return nil
+}
+
+// WriteTo writes the options that differ from the defaults in the format read by LoadConfig.
+// Values with whitespace are quoted. sphinxbase does not support escapes in quoted values,
+// so WriteTo fails without writing anything if a value has quotes or line breaks.
+func (c *Config) WriteTo(w io.Writer) (int64, error) {
+	values := c.Values()
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	buf := new(bytes.Buffer)
+	for _, name := range names {
+		var s string
+		switch v := values[name].(type) {
+		case bool:
+			s = "no"
+			if v {
+				s = "yes"
+			}
+		case float64:
+			s = strconv.FormatFloat(v, 'g', -1, 64)
+		case int:
+			s = strconv.Itoa(v)
+		case string:
+			s = v
+			if strings.ContainsAny(s, "\"'\r\n") {
+				return 0, &Error{Op: "Config.WriteTo", Arg: name, Err: fmt.Errorf("%w: %q cannot be quoted", ErrInvalidValue, s)}
+			}
+			if strings.ContainsAny(s, " \t") {
+				s = `"` + s + `"`
+			}
+		}
+		fmt.Fprintf(buf, "-%s %s\n", name, s)
+	}
+	return buf.WriteTo(w)
+}
+
+// MarshalJSON encodes the options that differ from the defaults as a JSON object,
+// see Config.Values().
+func (c *Config) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.Values())
+}
+
+// UnmarshalJSON sets the options from a JSON object. Values may be given as strings,
+// they are parsed according to the type of the option.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	var values map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&values); err != nil {
+		return err
+	}
+	return c.setValues("Config.UnmarshalJSON", values)
+}
+
+// MarshalYAML encodes the options that differ from the defaults as a YAML mapping,
+// see Config.Values(). Implements the yaml.Marshaler interface of gopkg.in/yaml.
+func (c *Config) MarshalYAML() (interface{}, error) {
+	return c.Values(), nil
+}
+
+// UnmarshalYAML sets the options from a YAML mapping. Implements the obsolete
+// yaml.Unmarshaler interface of gopkg.in/yaml, which is supported by all its versions.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var values map[string]interface{}
+	if err := unmarshal(&values); err != nil {
+		return err
+	}
+	return c.setValues("Config.UnmarshalYAML", values)
+}
+
+func (c *Config) setValues(op string, values map[string]interface{}) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := c.Set(name, values[name]); err != nil {
+			err.(*Error).Op = op
+			return err
+		}
+	}
+	return nil
+}
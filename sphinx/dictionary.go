@@ -0,0 +1,479 @@
+package sphinx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xlab/pocketsphinx-go/pocketsphinx"
+)
+
+// Pronunciation is a sequence of phones, e.g. HH AH L OW.
+type Pronunciation []string
+
+// ParsePronunciation splits a whitespace-separated list of phones.
+func ParsePronunciation(phones string) Pronunciation {
+	return Pronunciation(strings.Fields(phones))
+}
+
+func (p Pronunciation) String() string {
+	return strings.Join(p, " ")
+}
+
+// Equal reports whether both pronunciations have the same phones.
+func (p Pronunciation) Equal(other Pronunciation) bool {
+	if len(p) != len(other) {
+		return false
+	}
+	for i := range p {
+		if p[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Dictionary is a pronunciation dictionary in CMU format, as used for the main (-dict) and
+// the filler (-fdict) dictionaries of the decoder. Each line holds a word followed by its phones,
+// alternative pronunciations are numbered as word(2), word(3) and so on.
+//
+// Dictionary is independent from decoders, see Decoder.LoadDictionary() and
+// Decoder.AddDictionary() to use it for decoding. It is not safe for concurrent use.
+type Dictionary struct {
+	words map[string][]Pronunciation
+}
+
+// NewDictionary creates an empty dictionary.
+func NewDictionary() *Dictionary {
+	return &Dictionary{
+		words: make(map[string][]Pronunciation),
+	}
+}
+
+// ReadDictionary parses a dictionary. Lines starting with ## or ;; are comments,
+// as well as the rest of a line after a # separated by whitespace.
+func ReadDictionary(r io.Reader) (*Dictionary, error) {
+	dict := NewDictionary()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "##") || strings.HasPrefix(line, ";;") {
+			continue
+		}
+		fields := strings.Fields(line)
+		for i, f := range fields {
+			if strings.HasPrefix(f, "#") {
+				fields = fields[:i]
+				break
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) == 1 {
+			return nil, &Error{
+				Op:  "ReadDictionary",
+				Arg: fmt.Sprintf("line %d", n),
+				Err: fmt.Errorf("%w: no phones for %q", ErrInvalidDictionary, fields[0]),
+			}
+		}
+		dict.Add(BaseWord(fields[0]), Pronunciation(fields[1:]))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &Error{Op: "ReadDictionary", Err: fmt.Errorf("%w: %v", ErrInvalidDictionary, err)}
+	}
+	return dict, nil
+}
+
+// LoadDictionary reads a dictionary from a file, see ReadDictionary.
+func LoadDictionary(filename string) (*Dictionary, error) {
+	if err := checkFile("LoadDictionary", String(filename)); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, &Error{Op: "LoadDictionary", Arg: filename, Err: fmt.Errorf("%w: %v", ErrFileNotReadable, err)}
+	}
+	defer f.Close()
+	dict, err := ReadDictionary(f)
+	if err != nil {
+		err.(*Error).Arg = filename + ": " + err.(*Error).Arg
+		return nil, err
+	}
+	return dict, nil
+}
+
+// BaseWord strips the alternative pronunciation number from the word, e.g. word(2) becomes word.
+func BaseWord(word string) string {
+	if !strings.HasSuffix(word, ")") {
+		return word
+	}
+	i := strings.LastIndexByte(word, '(')
+	if i <= 0 {
+		return word
+	}
+	if _, err := strconv.Atoi(word[i+1 : len(word)-1]); err != nil {
+		return word
+	}
+	return word[:i]
+}
+
+// variantName gets the name of the i-th pronunciation of the word, counting from 0.
+func variantName(word string, i int) string {
+	if i == 0 {
+		return word
+	}
+	return fmt.Sprintf("%s(%d)", word, i+1)
+}
+
+// Len gets the number of words in the dictionary, not counting alternative pronunciations.
+func (d *Dictionary) Len() int {
+	return len(d.words)
+}
+
+// Words lists the words in the dictionary, sorted.
+func (d *Dictionary) Words() []string {
+	words := make([]string, 0, len(d.words))
+	for word := range d.words {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
+// Lookup gets the pronunciations of the word, the first one is the primary pronunciation.
+// Returns nil if there is no such word.
+func (d *Dictionary) Lookup(word string) []Pronunciation {
+	prons := d.words[BaseWord(word)]
+	if prons == nil {
+		return nil
+	}
+	return append([]Pronunciation(nil), prons...)
+}
+
+// Contains reports whether the dictionary has the word.
+func (d *Dictionary) Contains(word string) bool {
+	_, ok := d.words[BaseWord(word)]
+	return ok
+}
+
+// Add adds a pronunciation of the word, as an alternative if the word is already present.
+// Returns false if the word already has the same pronunciation.
+func (d *Dictionary) Add(word string, pron Pronunciation) bool {
+	word = BaseWord(word)
+	for _, p := range d.words[word] {
+		if p.Equal(pron) {
+			return false
+		}
+	}
+	d.words[word] = append(d.words[word], append(Pronunciation(nil), pron...))
+	return true
+}
+
+// Remove removes the word with all its pronunciations. Returns false if there is no such word.
+func (d *Dictionary) Remove(word string) bool {
+	word = BaseWord(word)
+	if _, ok := d.words[word]; !ok {
+		return false
+	}
+	delete(d.words, word)
+	return true
+}
+
+// RemovePronunciation removes a single pronunciation of the word, the word is removed
+// along with its last pronunciation. Returns false if the word has no such pronunciation.
+func (d *Dictionary) RemovePronunciation(word string, pron Pronunciation) bool {
+	word = BaseWord(word)
+	prons := d.words[word]
+	for i, p := range prons {
+		if p.Equal(pron) {
+			prons = append(prons[:i:i], prons[i+1:]...)
+			if len(prons) == 0 {
+				delete(d.words, word)
+			} else {
+				d.words[word] = prons
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Merge adds the pronunciations of the other dictionary missing from this one.
+func (d *Dictionary) Merge(other *Dictionary) {
+	for _, word := range other.Words() {
+		for _, pron := range other.words[word] {
+			d.Add(word, pron)
+		}
+	}
+}
+
+// DictionaryDiff lists the differences between two dictionaries, see Dictionary.Diff().
+type DictionaryDiff struct {
+	// Added lists the words present only in the other dictionary.
+	Added []string
+	// Removed lists the words missing from the other dictionary.
+	Removed []string
+	// Changed lists the words with different pronunciations, the order of alternatives matters.
+	Changed []string
+}
+
+// Diff compares the dictionary to the other one, the lists of words are sorted.
+func (d *Dictionary) Diff(other *Dictionary) DictionaryDiff {
+	var diff DictionaryDiff
+	for _, word := range d.Words() {
+		prons, ok := other.words[word]
+		if !ok {
+			diff.Removed = append(diff.Removed, word)
+			continue
+		}
+		changed := len(prons) != len(d.words[word])
+		for i := 0; !changed && i < len(prons); i++ {
+			changed = !prons[i].Equal(d.words[word][i])
+		}
+		if changed {
+			diff.Changed = append(diff.Changed, word)
+		}
+	}
+	for _, word := range other.Words() {
+		if _, ok := d.words[word]; !ok {
+			diff.Added = append(diff.Added, word)
+		}
+	}
+	return diff
+}
+
+// Phones lists the phones used in the dictionary, sorted.
+func (d *Dictionary) Phones() []string {
+	set := make(map[string]bool)
+	for _, prons := range d.words {
+		for _, pron := range prons {
+			for _, phone := range pron {
+				set[phone] = true
+			}
+		}
+	}
+	phones := make([]string, 0, len(set))
+	for phone := range set {
+		phones = append(phones, phone)
+	}
+	sort.Strings(phones)
+	return phones
+}
+
+// CheckPhones checks that the pronunciations use only the given phones, e.g. the ones
+// of the acoustic model read with ReadPhones(). All problems found are reported in
+// a *ValidationError, each as an *Error with the word as Arg and ErrBadPhone.
+func (d *Dictionary) CheckPhones(phones []string) error {
+	known := make(map[string]bool, len(phones))
+	for _, phone := range phones {
+		known[phone] = true
+	}
+	var errs []*Error
+	for _, word := range d.Words() {
+		for i, pron := range d.words[word] {
+			for _, phone := range pron {
+				if !known[phone] {
+					errs = append(errs, &Error{
+						Op:  "CheckPhones",
+						Arg: variantName(word, i),
+						Err: fmt.Errorf("%w: %s", ErrBadPhone, phone),
+					})
+				}
+			}
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: errs}
+}
+
+// WriteTo writes the dictionary in CMU format, sorted by word.
+func (d *Dictionary) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var total int64
+	for _, word := range d.Words() {
+		for i, pron := range d.words[word] {
+			n, err := fmt.Fprintf(bw, "%s %s\n", variantName(word, i), pron)
+			total += int64(n)
+			if err != nil {
+				return total, err
+			}
+		}
+	}
+	return total, bw.Flush()
+}
+
+// Binary model definition magic numbers, see bin_mdef.h.
+const (
+	binMdefMagic        = 0x46444d42
+	binMdefMagicSwapped = 0x424d4446
+)
+
+// ReadPhones reads the context-independent phones of the acoustic model from
+// its model definition file in text or binary format, e.g. the mdef file in the
+// model directory. Use Config.GetString("-mdef") to find the one used by the decoder.
+func ReadPhones(mdefFile string) ([]string, error) {
+	if err := checkFile("ReadPhones", String(mdefFile)); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(mdefFile)
+	if err != nil {
+		return nil, &Error{Op: "ReadPhones", Arg: mdefFile, Err: fmt.Errorf("%w: %v", ErrFileNotReadable, err)}
+	}
+	var phones []string
+	if len(data) >= 4 {
+		switch binary.LittleEndian.Uint32(data) {
+		case binMdefMagic:
+			phones, err = readBinMdefPhones(data, binary.LittleEndian)
+		case binMdefMagicSwapped:
+			phones, err = readBinMdefPhones(data, binary.BigEndian)
+		default:
+			phones, err = readTextMdefPhones(data)
+		}
+	}
+	if err == nil && len(phones) == 0 {
+		err = errors.New("no phones")
+	}
+	if err != nil {
+		return nil, &Error{Op: "ReadPhones", Arg: mdefFile, Err: fmt.Errorf("%w: %v", ErrInvalidModel, err)}
+	}
+	return phones, nil
+}
+
+// modelPhones gets the set of phones of the acoustic model of the decoder,
+// nil if its model definition cannot be read.
+func (d *Decoder) modelPhones() map[string]bool {
+	mdef := pocketsphinx.RawString(pocketsphinx.CommandLnStrR(pocketsphinx.GetConfig(d.dec), String("-mdef").S())).Copy()
+	if len(mdef) == 0 {
+		return nil
+	}
+	phones, err := ReadPhones(mdef)
+	if err != nil {
+		return nil
+	}
+	known := make(map[string]bool, len(phones))
+	for _, phone := range phones {
+		known[phone] = true
+	}
+	return known
+}
+
+// readBinMdefPhones reads the names of the CI phones following the header of a binary mdef.
+func readBinMdefPhones(data []byte, order binary.ByteOrder) ([]string, error) {
+	// magic, version and the length of the format description
+	if len(data) < 12 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	pos := 12 + int(order.Uint32(data[8:]))
+	// the counts of CI phones, phones, emitting states, CI senones, senones,
+	// transition matrices, senone sequences, contexts, CD trees and the silence phone
+	if pos < 12 || len(data) < pos+40 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	nCI := int(int32(order.Uint32(data[pos:])))
+	pos += 40
+	phones := make([]string, 0, nCI)
+	for i := 0; i < nCI; i++ {
+		end := bytes.IndexByte(data[pos:], 0)
+		if end < 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		phones = append(phones, string(data[pos:pos+end]))
+		pos += end + 1
+	}
+	return phones, nil
+}
+
+// readTextMdefPhones reads the base phones of a text mdef, they have no context: AA - - - ...
+func readTextMdefPhones(data []byte) ([]string, error) {
+	var phones []string
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if fields[1] == "-" && fields[2] == "-" && fields[3] == "-" {
+			phones = append(phones, fields[0])
+		}
+	}
+	return phones, nil
+}
+
+// LoadDictionary replaces the pronunciation dictionary of the decoder, see Decoder.ReadDict().
+// filler replaces the filler dictionary, or nil to keep the existing one.
+func (d *Decoder) LoadDictionary(dict, filler *Dictionary) error {
+	dictFile, err := writeTempDictionary(dict)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(dictFile)
+	var fillerFile string
+	if filler != nil {
+		if fillerFile, err = writeTempDictionary(filler); err != nil {
+			return err
+		}
+		defer os.Remove(fillerFile)
+	}
+	if err := d.ReadDict(String(dictFile), String(fillerFile)); err != nil {
+		err.(*Error).Op = "LoadDictionary"
+		err.(*Error).Arg = ""
+		return err
+	}
+	return nil
+}
+
+func writeTempDictionary(dict *Dictionary) (string, error) {
+	f, err := os.CreateTemp("", "sphinx-*.dict")
+	if err != nil {
+		return "", &Error{Op: "LoadDictionary", Err: fmt.Errorf("%w: %v", ErrFailed, err)}
+	}
+	_, err = dict.WriteTo(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", &Error{Op: "LoadDictionary", Err: fmt.Errorf("%w: %v", ErrFailed, err)}
+	}
+	return f.Name(), nil
+}
+
+// AddDictionary adds the words of dict to the pronunciation dictionary of the decoder,
+// see Decoder.AddWord(). The pronunciations already known to the decoder are skipped,
+// the active search is updated once all words are added. The words that could not be
+// added are reported in a *ValidationError.
+func (d *Decoder) AddDictionary(dict *Dictionary) error {
+	type entry struct {
+		name, phones string
+	}
+	var entries []entry
+	for _, word := range dict.Words() {
+		for i, pron := range dict.words[word] {
+			name := variantName(word, i)
+			if phones, ok := d.LookupWord(String(name)); ok && ParsePronunciation(phones).Equal(pron) {
+				continue
+			}
+			entries = append(entries, entry{name: name, phones: pron.String()})
+		}
+	}
+	var errs []*Error
+	for i, e := range entries {
+		if _, err := d.AddWord(String(e.name), String(e.phones), i == len(entries)-1); err != nil {
+			errs = append(errs, err.(*Error))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: errs}
+}
@@ -0,0 +1,63 @@
+package sphinx
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDictionaryRoundTrip(t *testing.T) {
+	cases := []struct {
+		name     string
+		in       string
+		expected string
+	}{
+		{"empty", "", ""},
+		{"sorted", "world W ER L D\nhello HH AH L OW\n", "hello HH AH L OW\nworld W ER L D\n"},
+		{
+			"alternatives",
+			"read R IY D\nread(2) R EH D\nread(3) R IY D\n",
+			"read R IY D\nread(2) R EH D\n",
+		},
+		{
+			"comments",
+			"## header\n;; note\n\nthe DH AH # unstressed\nthe(2)\tDH  IY\n",
+			"the DH AH\nthe(2) DH IY\n",
+		},
+		{"not a variant", "a(b) EY B IY\n", "a(b) EY B IY\n"},
+	}
+	for _, tc := range cases {
+		dict, err := ReadDictionary(strings.NewReader(tc.in))
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		var buf bytes.Buffer
+		n, err := dict.WriteTo(&buf)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if buf.String() != tc.expected || n != int64(buf.Len()) {
+			t.Errorf("%s: wrote %d bytes %q, expected %q", tc.name, n, buf.String(), tc.expected)
+			continue
+		}
+		again, err := ReadDictionary(&buf)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if diff := dict.Diff(again); diff.Added != nil || diff.Removed != nil || diff.Changed != nil {
+			t.Errorf("%s: round trip differs: %+v", tc.name, diff)
+		}
+	}
+}
+
+func TestReadDictionaryInvalid(t *testing.T) {
+	_, err := ReadDictionary(strings.NewReader("hello HH AH L OW\nworld\n"))
+	var e *Error
+	if !errors.Is(err, ErrInvalidDictionary) || !errors.As(err, &e) || e.Arg != "line 2" {
+		t.Errorf("got %v, expected %v at line 2", err, ErrInvalidDictionary)
+	}
+}
@@ -0,0 +1,129 @@
+package sphinx
+
+import (
+	"errors"
+	"os"
+	"strconv"
+
+	"github.com/xlab/pocketsphinx-go/pocketsphinx"
+)
+
+// Sentinel errors describing why an operation has failed, use errors.Is to check them.
+var (
+	// ErrFailed is reported when the underlying library failed for a reason
+	// not covered by other errors, see Error.Msg for the details.
+	ErrFailed = errors.New("operation failed")
+	// ErrNotStarted is reported when an utterance must be started first.
+	ErrNotStarted = errors.New("utterance not started")
+	// ErrAlreadyStarted is reported when an utterance has been already started.
+	ErrAlreadyStarted = errors.New("utterance already started")
+	// ErrUnknownSearch is reported when there is no search with the given name.
+	ErrUnknownSearch = errors.New("unknown search")
+	// ErrFileNotFound is reported when the file to read does not exist.
+	ErrFileNotFound = errors.New("file not found")
+	// ErrFileNotReadable is reported when a file exists but cannot be read.
+	ErrFileNotReadable = errors.New("file not readable")
+	// ErrBadPhone is reported when a pronunciation contains phones unknown to the acoustic model.
+	ErrBadPhone = errors.New("unknown phone")
+	// ErrWordExists is reported when a word is already present in the dictionary.
+	ErrWordExists = errors.New("word already exists")
+	// ErrUnknownWord is reported when a word is not in the vocabulary.
+	ErrUnknownWord = errors.New("unknown word")
+	// ErrBadState is reported when a grammar state is out of range.
+	ErrBadState = errors.New("invalid state")
+	// ErrBadProbability is reported when a probability is not in the (0, 1] range.
+	ErrBadProbability = errors.New("invalid probability")
+	// ErrNoRule is reported when a grammar has no rule to start from.
+	ErrNoRule = errors.New("no such rule")
+	// ErrInvalidWAV is reported when a WAV stream is malformed.
+	ErrInvalidWAV = errors.New("invalid WAV stream")
+	// ErrUnsupportedFormat is reported when the audio sample format is not supported.
+	ErrUnsupportedFormat = errors.New("unsupported audio format")
+	// ErrPoolClosed is reported when a decoder is acquired from a closed pool.
+	ErrPoolClosed = errors.New("decoder pool closed")
+	// ErrBadSampleRate is reported when a sample rate is not positive.
+	ErrBadSampleRate = errors.New("invalid sample rate")
+	// ErrNoPronunciation is reported when the pronunciation of a word cannot be generated.
+	ErrNoPronunciation = errors.New("no pronunciation")
+	// ErrInvalidDictionary is reported when a pronunciation dictionary is malformed.
+	ErrInvalidDictionary = errors.New("invalid dictionary")
+	// ErrInvalidGrammar is reported when a grammar is malformed.
+	ErrInvalidGrammar = errors.New("invalid grammar")
+	// ErrInvalidModel is reported when a model file is malformed.
+	ErrInvalidModel = errors.New("invalid model")
+	// ErrBadFrame is reported when a frame of features has a wrong number of values.
+	ErrBadFrame = errors.New("invalid frame size")
+	// ErrUnknownOption is reported when a configuration option does not exist.
+	ErrUnknownOption = errors.New("unknown option")
+	// ErrInvalidValue is reported when a configuration value cannot be converted to the option type.
+	ErrInvalidValue = errors.New("invalid option value")
+	// ErrMissingOption is reported when a required configuration option is not set.
+	ErrMissingOption = errors.New("missing required option")
+)
+
+// Error records a failed operation, its argument and the cause.
+type Error struct {
+	// Op is the name of the operation, e.g. "StartUtt" or "ReadDict".
+	Op string
+	// Arg is the argument of the operation, e.g. a search name or a file path, may be empty.
+	Arg string
+	// Err is one of the sentinel errors describing the failure, possibly wrapped with the details.
+	Err error
+	// Msg is the last error message reported by sphinxbase during the operation, if any.
+	Msg string
+}
+
+func (e *Error) Error() string {
+	s := "sphinx: " + e.Op
+	if len(e.Arg) > 0 {
+		s += " " + strconv.Quote(e.Arg)
+	}
+	s += ": " + e.Err.Error()
+	if len(e.Msg) > 0 {
+		s += " (" + e.Msg + ")"
+	}
+	return s
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func init() {
+	pocketsphinx.CaptureErrors()
+}
+
+// errMark marks the point after which sphinxbase error messages are attributed to an operation.
+type errMark uint64
+
+func markErrors() errMark {
+	_, seq := pocketsphinx.LastError()
+	return errMark(seq)
+}
+
+// newError creates an Error, attaching the last sphinxbase error message if it
+// has been reported after the mark. Note that with concurrent use of the library
+// the message may come from another operation.
+func newError(mark errMark, op, arg string, err error) *Error {
+	e := &Error{
+		Op:  op,
+		Arg: arg,
+		Err: err,
+	}
+	if msg, seq := pocketsphinx.LastError(); errMark(seq) != mark {
+		e.Msg = msg
+	}
+	return e
+}
+
+// checkFile reports ErrFileNotFound if the file to be read by an operation does not exist.
+func checkFile(op string, filename String) error {
+	if _, err := os.Stat(string(filename)); os.IsNotExist(err) {
+		return &Error{
+			Op:  op,
+			Arg: string(filename),
+			Err: ErrFileNotFound,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package sphinx
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/xlab/pocketsphinx-go/pocketsphinx"
+)
+
+// FeatureComputer turns cepstra, e.g. computed by FrontEnd, into the feature streams the
+// acoustic model sees, such as 1s_c_d_dd: the cepstra normalized with CMN and AGC, extended
+// with the dynamic (delta) coefficients, and projected with the LDA or MLLT transform of
+// the model if any.
+//
+// The features of a frame depend on the frames around it, see FeatureComputer.WindowSize(),
+// so in the streaming mode they lag behind the input. A feature computer keeps the state
+// of the stream and the live normalization statistics, it is not safe for concurrent use.
+type FeatureComputer struct {
+	f *pocketsphinx.Feat
+	// owned is set when the wrapper holds its own reference to f.
+	owned bool
+	// started is set between the first frames of an utterance and FeatureComputer.EndUtt().
+	started bool
+}
+
+// ownFeatureComputer wraps a feature computation reference owned by the caller.
+func ownFeatureComputer(f *pocketsphinx.Feat) *FeatureComputer {
+	fc := &FeatureComputer{
+		f:     f,
+		owned: true,
+	}
+	runtime.SetFinalizer(fc, func(fc *FeatureComputer) {
+		reportLeak("FeatureComputer")
+		fc.Close()
+	})
+	return fc
+}
+
+// NewFeatureComputer creates a feature computer configured by the same options as the decoder,
+// including the feature parameters and the feature transform of the acoustic model set by -hmm.
+// The configuration is not claimed and can be used afterwards, pass nil to use the defaults.
+func NewFeatureComputer(cfg *Config) (*FeatureComputer, error) {
+	if cfg == nil {
+		cfg = NewConfig()
+		defer cfg.Close()
+	}
+	mark := markErrors()
+	f := pocketsphinx.FeatInitConfig(cfg.resolved())
+	if f == nil {
+		err := newError(mark, "NewFeatureComputer", "", ErrFailed)
+		if verr := cfg.validate(false); verr != nil {
+			err.Err = fmt.Errorf("%w: %w", ErrFailed, verr)
+		}
+		return nil, err
+	}
+	return ownFeatureComputer(f), nil
+}
+
+// FeatureComputer gets the feature computer of the decoder.
+//
+// The decoder retains ownership of this object, closing it is a no-op.
+// Use FeatureComputer.Retain() if you wish to reuse it elsewhere. Note that the decoder
+// uses it to process audio and cepstra, so it must not be used during an utterance.
+func (d *Decoder) FeatureComputer() *FeatureComputer {
+	return &FeatureComputer{
+		f: pocketsphinx.GetFeat(d.dec),
+	}
+}
+
+// Feat returns a retained copy of underlying reference to pocketsphinx.Feat.
+func (fc *FeatureComputer) Feat() *pocketsphinx.Feat {
+	return pocketsphinx.FeatRetain(fc.f)
+}
+
+// Name gets the feature type, e.g. "1s_c_d_dd".
+func (fc *FeatureComputer) Name() string {
+	return pocketsphinx.FeatName(fc.f)
+}
+
+// InputSize gets the number of values in an input frame of cepstra.
+func (fc *FeatureComputer) InputSize() int {
+	return int(pocketsphinx.FeatCepsize(fc.f))
+}
+
+// WindowSize gets the number of frames on each side of a frame needed to compute its features.
+func (fc *FeatureComputer) WindowSize() int {
+	return int(pocketsphinx.FeatWindowSize(fc.f))
+}
+
+// Streams gets the number of values in each feature stream, or subvector if the
+// -svspec option is set. With a feature transform there is a single stream.
+func (fc *FeatureComputer) Streams() []int {
+	n := pocketsphinx.FeatDimension1(fc.f)
+	dims := make([]int, 0, n)
+	for i := int32(0); i < n; i++ {
+		dims = append(dims, int(pocketsphinx.FeatDimension2(fc.f, i)))
+	}
+	return dims
+}
+
+// OutputSize gets the total number of values in a frame of features.
+func (fc *FeatureComputer) OutputSize() int {
+	var size int
+	for _, dim := range fc.Streams() {
+		size += dim
+	}
+	return size
+}
+
+// Transforms gets the number of linear transforms applied to the features, e.g. LDA or MLLT.
+func (fc *FeatureComputer) Transforms() int {
+	return int(pocketsphinx.FeatNLda(fc.f))
+}
+
+// ReadTransform adds a linear transform of the features, e.g. LDA or MLLT, read from a file
+// in the format of the feature_transform file of the acoustic models. dim is the number of
+// values of the transformed features, or 0 to use the entire matrix.
+func (fc *FeatureComputer) ReadTransform(filename String, dim int) error {
+	if err := checkFile("FeatureComputer.ReadTransform", filename); err != nil {
+		return err
+	}
+	mark := markErrors()
+	if ret := pocketsphinx.FeatReadLda(fc.f, string(filename), int32(dim)); ret < 0 {
+		return newError(mark, "FeatureComputer.ReadTransform", string(filename), ErrFailed)
+	}
+	return nil
+}
+
+// ProcessFrames computes the features of a block of cepstra in the streaming mode, with
+// live CMN and AGC. Because of the window of the dynamic coefficients, the features of
+// the last frames are returned by the next call or by FeatureComputer.EndUtt().
+//
+// Every frame of features is split into the streams, see FeatureComputer.Streams().
+func (fc *FeatureComputer) ProcessFrames(cep [][]float32) ([][][]float32, error) {
+	return fc.process("FeatureComputer.ProcessFrames", cep, !fc.started, false)
+}
+
+// EndUtt finishes the utterance, returns the features of the frames left over
+// and updates the live normalization statistics.
+func (fc *FeatureComputer) EndUtt() ([][][]float32, error) {
+	feat, err := fc.process("FeatureComputer.EndUtt", nil, !fc.started, true)
+	pocketsphinx.FeatUpdateStats(fc.f)
+	return feat, err
+}
+
+// ProcessUtt computes the features of a whole utterance at once, with batch CMN and AGC.
+func (fc *FeatureComputer) ProcessUtt(cep [][]float32) ([][][]float32, error) {
+	fc.started = false
+	feat, err := fc.process("FeatureComputer.ProcessUtt", cep, true, true)
+	pocketsphinx.FeatUpdateStats(fc.f)
+	return feat, err
+}
+
+func (fc *FeatureComputer) process(op string, cep [][]float32, beginUtt, endUtt bool) ([][][]float32, error) {
+	ceplen := fc.InputSize()
+	buf := make([]float32, 0, len(cep)*ceplen)
+	for i, frame := range cep {
+		if len(frame) != ceplen {
+			return nil, &Error{Op: op, Arg: fmt.Sprintf("frame %d", i), Err: fmt.Errorf("%w: %d values, expected %d", ErrBadFrame, len(frame), ceplen)}
+		}
+		buf = append(buf, frame...)
+	}
+	live := func(cep []float32, beginUtt bool, out []float32) (consumed, nframes int32) {
+		return pocketsphinx.FeatS2mfc2featLive(fc.f, cep, beginUtt, endUtt, out)
+	}
+	feat, err := collectFeatures(live, buf, ceplen, fc.WindowSize(), fc.Streams(), beginUtt)
+	if err != nil {
+		return feat, &Error{Op: op, Err: err}
+	}
+	fc.started = !endUtt
+	return feat, nil
+}
+
+// collectFeatures computes the features of the cepstra stored one after another in buf with live,
+// which consumes only as many frames as sphinxbase processes at once, so it is called until
+// all of them are consumed.
+func collectFeatures(live func(cep []float32, beginUtt bool, out []float32) (consumed, nframes int32),
+	buf []float32, ceplen, window int, streams []int, beginUtt bool) ([][][]float32, error) {
+	var size int
+	for _, dim := range streams {
+		size += dim
+	}
+	var feat [][][]float32
+	for {
+		// the frames returned are slices of out, so it cannot be reused by the next call
+		out := make([]float32, (len(buf)/ceplen+window)*size)
+		consumed, nframes := live(buf, beginUtt, out)
+		if nframes < 0 {
+			return feat, ErrFailed
+		}
+		for _, frame := range splitFrames(out, int(nframes), size) {
+			feat = append(feat, splitStreams(frame, streams))
+		}
+		buf = buf[int(consumed)*ceplen:]
+		// the end of the utterance is not processed if the frames did not fit in the buffer
+		if len(buf) == 0 || consumed == 0 {
+			break
+		}
+		beginUtt = false
+	}
+	return feat, nil
+}
+
+// splitStreams slices a frame of features into the streams of the given sizes.
+func splitStreams(frame []float32, streams []int) [][]float32 {
+	split := make([][]float32, 0, len(streams))
+	for _, dim := range streams {
+		split = append(split, frame[:dim:dim])
+		frame = frame[dim:]
+	}
+	return split
+}
+
+// Retain gets a new reference to the feature computer owned by the caller, it must be closed
+// when no longer needed.
+func (fc *FeatureComputer) Retain() *FeatureComputer {
+	return ownFeatureComputer(pocketsphinx.FeatRetain(fc.f))
+}
+
+// Close releases the reference to the feature computer, it is a no-op for borrowed ones.
+func (fc *FeatureComputer) Close() error {
+	if fc.f == nil || !fc.owned {
+		return nil
+	}
+	runtime.SetFinalizer(fc, nil)
+	pocketsphinx.FeatFree(fc.f)
+	fc.f = nil
+	return nil
+}
@@ -0,0 +1,74 @@
+package sphinx
+
+import "testing"
+
+func TestCollectFeatures(t *testing.T) {
+	const (
+		ceplen  = 2
+		window  = 3
+		nframes = 700
+		// the number of frames sphinxbase processes at once
+		blockSize = 256
+	)
+	streams := []int{2, 1}
+	buf := make([]float32, 0, nframes*ceplen)
+	for i := 0; i < nframes; i++ {
+		buf = append(buf, float32(i), -float32(i))
+	}
+	// live stands for feat_s2mfc2feat_live, every frame of features is filled with the
+	// first value of the frame of cepstra
+	var calls, begins int
+	live := func(cep []float32, beginUtt bool, out []float32) (consumed, nframes int32) {
+		calls++
+		if beginUtt {
+			begins++
+		}
+		n := len(cep) / ceplen
+		if n > blockSize {
+			n = blockSize
+		}
+		if len(out) < (n+window)*3 {
+			t.Fatalf("call %d: %d values of output for %d frames", calls, len(out), n)
+		}
+		for i := 0; i < n; i++ {
+			for k := 0; k < 3; k++ {
+				out[i*3+k] = cep[i*ceplen]
+			}
+		}
+		return int32(n), int32(n)
+	}
+	feat, err := collectFeatures(live, buf, ceplen, window, streams, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 || begins != 1 {
+		t.Errorf("%d calls starting %d utterances, expected 3 calls starting 1", calls, begins)
+	}
+	if len(feat) != nframes {
+		t.Fatalf("got %d frames, expected %d", len(feat), nframes)
+	}
+	for i, frame := range feat {
+		if len(frame) != 2 || len(frame[0]) != 2 || len(frame[1]) != 1 {
+			t.Fatalf("frame %d: streams of %d, %d values", i, len(frame[0]), len(frame[1]))
+		}
+		if frame[0][0] != float32(i) || frame[0][1] != float32(i) || frame[1][0] != float32(i) {
+			t.Errorf("frame %d: got %v, expected %v", i, frame, i)
+		}
+	}
+}
+
+func TestCollectFeaturesFailure(t *testing.T) {
+	buf := make([]float32, 300*13)
+	var calls int
+	live := func(cep []float32, beginUtt bool, out []float32) (consumed, nframes int32) {
+		calls++
+		if calls > 1 {
+			return 0, -1
+		}
+		return 256, 256
+	}
+	feat, err := collectFeatures(live, buf, 13, 3, []int{39}, true)
+	if err != ErrFailed || len(feat) != 256 {
+		t.Errorf("got %d frames and %v, expected 256 frames and %v", len(feat), err, ErrFailed)
+	}
+}
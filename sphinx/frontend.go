@@ -0,0 +1,242 @@
+package sphinx
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/xlab/pocketsphinx-go/pocketsphinx"
+)
+
+// FrontEnd computes acoustic features from audio: MFCC frames, or log spectrum frames
+// if the -logspec or -smoothspec option is enabled. The frames can be passed to
+// Decoder.ProcessCep(), so the features of an utterance can be computed once and
+// decoded many times, e.g. with different searches.
+//
+// A front-end keeps the state of the stream and is not safe for concurrent use.
+type FrontEnd struct {
+	fe *pocketsphinx.Fe
+	// owned is set when the wrapper holds its own reference to fe.
+	owned bool
+}
+
+// ownFrontEnd wraps a front-end reference owned by the caller.
+func ownFrontEnd(fe *pocketsphinx.Fe) *FrontEnd {
+	f := &FrontEnd{
+		fe:    fe,
+		owned: true,
+	}
+	runtime.SetFinalizer(f, func(f *FrontEnd) {
+		reportLeak("FrontEnd")
+		f.Close()
+	})
+	return f
+}
+
+// NewFrontEnd creates a front-end configured by the same options as the decoder, including
+// the feature parameters of the acoustic model set by -hmm, so the frames match the ones
+// the decoder would compute. The configuration is not claimed and can be used afterwards,
+// pass nil to use the defaults.
+func NewFrontEnd(cfg *Config) (*FrontEnd, error) {
+	if cfg == nil {
+		cfg = NewConfig()
+		defer cfg.Close()
+	}
+	mark := markErrors()
+	// the front-end claims the configuration, so it gets its own reference
+	fe := pocketsphinx.FeInitAutoR(pocketsphinx.CommandLnRetain(cfg.resolved()))
+	if fe == nil {
+		err := newError(mark, "NewFrontEnd", "", ErrFailed)
+		if verr := cfg.validate(false); verr != nil {
+			err.Err = fmt.Errorf("%w: %w", ErrFailed, verr)
+		}
+		return nil, err
+	}
+	return ownFrontEnd(fe), nil
+}
+
+// FrontEnd gets the front-end of the decoder.
+//
+// The decoder retains ownership of this object, closing it is a no-op.
+// Use FrontEnd.Retain() if you wish to reuse it elsewhere. Note that the decoder uses
+// the front-end to process raw audio, so it must not be used during an utterance.
+func (d *Decoder) FrontEnd() *FrontEnd {
+	return &FrontEnd{
+		fe: pocketsphinx.GetFe(d.dec),
+	}
+}
+
+// Fe returns a retained copy of underlying reference to pocketsphinx.Fe.
+func (f *FrontEnd) Fe() *pocketsphinx.Fe {
+	return pocketsphinx.FeRetain(f.fe)
+}
+
+// OutputSize gets the number of values in a frame, the number of cepstral coefficients
+// or of the log spectrum bins.
+func (f *FrontEnd) OutputSize() int {
+	return int(pocketsphinx.FeGetOutputSize(f.fe))
+}
+
+// InputSize gets the number of samples between the starts of the frames, and in a frame.
+// To get N frames, at least (N-1)*frameShift+frameSize samples are needed.
+func (f *FrontEnd) InputSize() (frameShift, frameSize int) {
+	shift, size := pocketsphinx.FeGetInputSize(f.fe)
+	return int(shift), int(size)
+}
+
+// LogSpectrum reports whether the frames are log spectra rather than MFCC.
+func (f *FrontEnd) LogSpectrum() bool {
+	ln := pocketsphinx.FeGetConfig(f.fe)
+	return pocketsphinx.CommandLnIntR(ln, String("-logspec").S()) != 0 ||
+		pocketsphinx.CommandLnIntR(ln, String("-smoothspec").S()) != 0
+}
+
+// InSpeech reports whether the last processed frame is speech, if voice activity
+// detection is enabled with the -remove_silence option.
+func (f *FrontEnd) InSpeech() bool {
+	return pocketsphinx.FeGetVadState(f.fe) != 0
+}
+
+// StartStream starts processing of a new stream of audio, resetting the frame counter.
+func (f *FrontEnd) StartStream() {
+	pocketsphinx.FeStartStream(f.fe)
+}
+
+// StartUtt starts processing of an utterance.
+func (f *FrontEnd) StartUtt() error {
+	mark := markErrors()
+	if ret := pocketsphinx.FeStartUtt(f.fe); ret < 0 {
+		return newError(mark, "FrontEnd.StartUtt", "", ErrFailed)
+	}
+	return nil
+}
+
+// ProcessFrames computes the frames of a block of samples in the streaming mode. The samples
+// left over after the last complete frame are kept for the next call or for FrontEnd.EndUtt().
+// ErrFailed is reported with the frames computed so far if the front-end stops taking samples.
+func (f *FrontEnd) ProcessFrames(samples []int16) ([][]float32, error) {
+	ncep := f.OutputSize()
+	var frames [][]float32
+	for len(samples) > 0 {
+		mark := markErrors()
+		_, n, _, ret := pocketsphinx.FeProcessFrames(f.fe, samples, nil)
+		if ret < 0 {
+			return frames, newError(mark, "FrontEnd.ProcessFrames", "", ErrFailed)
+		}
+		if n < 1 {
+			// the samples are short of a frame, they are still buffered
+			n = 1
+		}
+		buf := make([]float32, int(n)*ncep)
+		left, nframes, _, ret := pocketsphinx.FeProcessFrames(f.fe, samples, buf)
+		if ret < 0 {
+			return frames, newError(mark, "FrontEnd.ProcessFrames", "", ErrFailed)
+		}
+		frames = append(frames, splitFrames(buf, int(nframes), ncep)...)
+		if left == len(samples) && nframes == 0 {
+			// the front-end took none of the samples, they would be lost
+			return frames, &Error{
+				Op:  "FrontEnd.ProcessFrames",
+				Arg: fmt.Sprintf("%d samples", left),
+				Err: fmt.Errorf("%w: samples not consumed", ErrFailed),
+			}
+		}
+		samples = samples[len(samples)-left:]
+	}
+	return frames, nil
+}
+
+// EndUtt finishes the utterance, returns the last frame padded with zeros
+// from the samples left over, if any.
+func (f *FrontEnd) EndUtt() ([][]float32, error) {
+	buf := make([]float32, f.OutputSize())
+	mark := markErrors()
+	nframes, ret := pocketsphinx.FeEndUtt(f.fe, buf)
+	if ret < 0 {
+		return nil, newError(mark, "FrontEnd.EndUtt", "", ErrFailed)
+	}
+	return splitFrames(buf, int(nframes), len(buf)), nil
+}
+
+// ProcessUtt computes all the frames of an utterance at once, including the last
+// partial frame, ready to be passed to Decoder.ProcessCep() with fullUtterance set.
+func (f *FrontEnd) ProcessUtt(samples []int16) ([][]float32, error) {
+	if err := f.StartUtt(); err != nil {
+		return nil, err
+	}
+	mark := markErrors()
+	buf, nframes, ret := pocketsphinx.FeProcessUtt(f.fe, samples)
+	if ret < 0 {
+		return nil, newError(mark, "FrontEnd.ProcessUtt", "", ErrFailed)
+	}
+	frames := splitFrames(buf, int(nframes), f.OutputSize())
+	last, err := f.EndUtt()
+	if err != nil {
+		return frames, err
+	}
+	return append(frames, last...), nil
+}
+
+// LogSpectrumToMFCC converts a frame of log spectrum, as computed with the -logspec option,
+// to MFCC. The legacy DCT of sphinxbase is used unless dct2 is set, then the unitary DCT-II
+// which can be inverted with FrontEnd.MFCCToLogSpectrum() is used.
+func (f *FrontEnd) LogSpectrumToMFCC(spec []float32, dct2 bool) ([]float32, error) {
+	ln := pocketsphinx.FeGetConfig(f.fe)
+	nfilt := pocketsphinx.CommandLnIntR(ln, String("-nfilt").S())
+	if len(spec) != nfilt {
+		return nil, &Error{Op: "FrontEnd.LogSpectrumToMFCC", Err: fmt.Errorf("%w: %d values, expected %d", ErrBadFrame, len(spec), nfilt)}
+	}
+	cep := make([]float32, pocketsphinx.CommandLnIntR(ln, String("-ncep").S()))
+	mark := markErrors()
+	var ret int32
+	if dct2 {
+		ret = pocketsphinx.FeLogspecDct2(f.fe, spec, cep)
+	} else {
+		ret = pocketsphinx.FeLogspecToMfcc(f.fe, spec, cep)
+	}
+	if ret < 0 {
+		return nil, newError(mark, "FrontEnd.LogSpectrumToMFCC", "", ErrFailed)
+	}
+	return cep, nil
+}
+
+// MFCCToLogSpectrum converts a frame of MFCC to log spectrum with the unitary DCT-III,
+// the inverse of FrontEnd.LogSpectrumToMFCC() with dct2 set.
+func (f *FrontEnd) MFCCToLogSpectrum(cep []float32) ([]float32, error) {
+	ln := pocketsphinx.FeGetConfig(f.fe)
+	ncep := pocketsphinx.CommandLnIntR(ln, String("-ncep").S())
+	if len(cep) != ncep {
+		return nil, &Error{Op: "FrontEnd.MFCCToLogSpectrum", Err: fmt.Errorf("%w: %d values, expected %d", ErrBadFrame, len(cep), ncep)}
+	}
+	spec := make([]float32, pocketsphinx.CommandLnIntR(ln, String("-nfilt").S()))
+	mark := markErrors()
+	if ret := pocketsphinx.FeMfccDct3(f.fe, cep, spec); ret < 0 {
+		return nil, newError(mark, "FrontEnd.MFCCToLogSpectrum", "", ErrFailed)
+	}
+	return spec, nil
+}
+
+// splitFrames slices the frames stored one after another in buf.
+func splitFrames(buf []float32, nframes, size int) [][]float32 {
+	frames := make([][]float32, 0, nframes)
+	for i := 0; i < nframes; i++ {
+		frames = append(frames, buf[i*size:(i+1)*size:(i+1)*size])
+	}
+	return frames
+}
+
+// Retain gets a new reference to the front-end owned by the caller, it must be closed
+// when no longer needed.
+func (f *FrontEnd) Retain() *FrontEnd {
+	return ownFrontEnd(pocketsphinx.FeRetain(f.fe))
+}
+
+// Close releases the reference to the front-end, it is a no-op for borrowed front-ends.
+func (f *FrontEnd) Close() error {
+	if f.fe == nil || !f.owned {
+		return nil
+	}
+	runtime.SetFinalizer(f, nil)
+	pocketsphinx.FeFree(f.fe)
+	f.fe = nil
+	return nil
+}
@@ -0,0 +1,349 @@
+package sphinx
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+
+	"github.com/xlab/pocketsphinx-go/pocketsphinx"
+)
+
+// FSG is a word-level finite state grammar. States are numbered from 0 to States()-1,
+// a transition either emits a word or is a null (epsilon) transition, and has a given
+// probability of being taken.
+type FSG struct {
+	f *pocketsphinx.FsgModel
+	// owned is set when the wrapper holds its own reference to f.
+	owned bool
+	// lmath is retained for the grammars created here, sphinxbase does not retain it.
+	lmath *pocketsphinx.Logmath
+}
+
+// ownFSG wraps a grammar reference owned by the caller, retaining lmath if not nil.
+func ownFSG(f *pocketsphinx.FsgModel, lmath *pocketsphinx.Logmath) *FSG {
+	fsg := &FSG{
+		f:     f,
+		owned: true,
+	}
+	if lmath != nil {
+		fsg.lmath = pocketsphinx.LogmathRetain(lmath)
+	}
+	runtime.SetFinalizer(fsg, func(f *FSG) {
+		reportLeak("FSG")
+		f.Close()
+	})
+	return fsg
+}
+
+// NewFSG creates an empty grammar with the given number of states, the start and
+// the final states are both 0 until set.
+//
+// lmath carries log-math parameters to use for probability calculations, or nil to use
+// the defaults of the decoder. lw is the language weight applied to transition probabilities.
+func NewFSG(name string, lmath *LogMath, lw float32, states int) (*FSG, error) {
+	if states < 1 {
+		return nil, &Error{Op: "NewFSG", Arg: name, Err: fmt.Errorf("%w: %d states", ErrBadState, states)}
+	}
+	m := logMathOrDefault(lmath)
+	defer pocketsphinx.LogmathFree(m)
+	mark := markErrors()
+	f := pocketsphinx.FsgModelInit(String(name).S(), m, lw, int32(states))
+	if f == nil {
+		return nil, newError(mark, "NewFSG", name, ErrFailed)
+	}
+	return ownFSG(f, m), nil
+}
+
+// logMathOrDefault gets a reference to the log-math parameters, or to new ones with the
+// defaults of the decoder if lmath is nil, to be freed by the caller.
+func logMathOrDefault(lmath *LogMath) *pocketsphinx.Logmath {
+	if lmath != nil {
+		return pocketsphinx.LogmathRetain(lmath.m)
+	}
+	return pocketsphinx.LogmathInit(defaultLogBase, 0, 0)
+}
+
+// NewFSGFromFile reads a word-level finite state grammar from a file in
+// Sphinx FSG format.
+//
+// lmath carries log-math parameters to use for probability calculations, or nil to use
+// the defaults of the decoder. lw is the language weight applied to transition probabilities.
+func NewFSGFromFile(filename String, lmath *LogMath, lw float32) (*FSG, error) {
+	if err := checkFile("NewFSGFromFile", filename); err != nil {
+		return nil, err
+	}
+	m := logMathOrDefault(lmath)
+	defer pocketsphinx.LogmathFree(m)
+	mark := markErrors()
+	f := pocketsphinx.FsgModelReadfile(filename.S(), m, lw)
+	if f == nil {
+		err := newError(mark, "NewFSGFromFile", string(filename), ErrFailed)
+		return nil, err
+	}
+	return ownFSG(f, m), nil
+}
+
+// FsgModel returns a retained copy of underlying reference to pocketsphinx.FsgModel.
+func (f *FSG) FsgModel() *pocketsphinx.FsgModel {
+	return pocketsphinx.FsgModelRetain(f.f)
+}
+
+// Name gets the name of the grammar.
+func (f *FSG) Name() string {
+	return pocketsphinx.FsgModelName(f.f)
+}
+
+// States gets the number of states.
+func (f *FSG) States() int {
+	return int(pocketsphinx.FsgModelNState(f.f))
+}
+
+// Start gets the start state.
+func (f *FSG) Start() int {
+	return int(pocketsphinx.FsgModelStartState(f.f))
+}
+
+// SetStart sets the start state.
+func (f *FSG) SetStart(state int) error {
+	if err := f.checkState("SetStart", state); err != nil {
+		return err
+	}
+	pocketsphinx.FsgModelSetStartState(f.f, int32(state))
+	return nil
+}
+
+// Final gets the final state.
+func (f *FSG) Final() int {
+	return int(pocketsphinx.FsgModelFinalState(f.f))
+}
+
+// SetFinal sets the final state.
+func (f *FSG) SetFinal(state int) error {
+	if err := f.checkState("SetFinal", state); err != nil {
+		return err
+	}
+	pocketsphinx.FsgModelSetFinalState(f.f, int32(state))
+	return nil
+}
+
+// LanguageWeight gets the language weight applied to the transition probabilities.
+func (f *FSG) LanguageWeight() float32 {
+	return pocketsphinx.FsgModelLw(f.f)
+}
+
+func (f *FSG) checkState(op string, states ...int) error {
+	for _, state := range states {
+		if state < 0 || state >= f.States() {
+			return &Error{Op: op, Arg: fmt.Sprintf("state %d", state), Err: ErrBadState}
+		}
+	}
+	return nil
+}
+
+// Words lists the vocabulary of the grammar, indexed by word ID.
+func (f *FSG) Words() []string {
+	n := pocketsphinx.FsgModelNWord(f.f)
+	words := make([]string, 0, n)
+	for wid := int32(0); wid < n; wid++ {
+		words = append(words, pocketsphinx.FsgModelWordStr(f.f, wid))
+	}
+	return words
+}
+
+// WordID gets the ID of the word in the vocabulary of the grammar.
+func (f *FSG) WordID(word string) (int, bool) {
+	wid := pocketsphinx.FsgModelWordId(f.f, String(word).S())
+	return int(wid), wid >= 0
+}
+
+// AddWord adds a word to the vocabulary if it is not there yet, returns its ID.
+func (f *FSG) AddWord(word string) int {
+	return int(pocketsphinx.FsgModelWordAdd(f.f, String(word).S()))
+}
+
+// logProb converts the probability to the scaled log domain used for transitions.
+func (f *FSG) logProb(op string, prob float64) (int32, error) {
+	if !(prob > 0 && prob <= 1) {
+		return 0, &Error{Op: op, Arg: fmt.Sprint(prob), Err: ErrBadProbability}
+	}
+	lmath := pocketsphinx.FsgModelLogmath(f.f)
+	return int32(float64(pocketsphinx.LogmathLog(lmath, prob)) * float64(f.LanguageWeight())), nil
+}
+
+// AddTransition adds a transition emitting the word, which is added to the vocabulary if needed.
+func (f *FSG) AddTransition(from, to int, prob float64, word string) error {
+	if err := f.checkState("AddTransition", from, to); err != nil {
+		return err
+	}
+	logp, err := f.logProb("AddTransition", prob)
+	if err != nil {
+		return err
+	}
+	wid := pocketsphinx.FsgModelWordAdd(f.f, String(word).S())
+	pocketsphinx.FsgModelTransAdd(f.f, int32(from), int32(to), logp, wid)
+	return nil
+}
+
+// AddNullTransition adds a null transition, which emits no word. If there is a null
+// transition between the states already, the more likely one is kept.
+func (f *FSG) AddNullTransition(from, to int, prob float64) error {
+	if err := f.checkState("AddNullTransition", from, to); err != nil {
+		return err
+	}
+	logp, err := f.logProb("AddNullTransition", prob)
+	if err != nil {
+		return err
+	}
+	pocketsphinx.FsgModelNullTransAdd(f.f, int32(from), int32(to), logp)
+	return nil
+}
+
+// AddSilence adds self-loops emitting the silence or filler word to the state, or to every
+// state if state is -1. Returns the number of transitions added.
+func (f *FSG) AddSilence(word string, state int, prob float64) (int, error) {
+	if state != -1 {
+		if err := f.checkState("AddSilence", state); err != nil {
+			return 0, err
+		}
+	}
+	if !(prob > 0 && prob <= 1) {
+		return 0, &Error{Op: "AddSilence", Arg: fmt.Sprint(prob), Err: ErrBadProbability}
+	}
+	return int(pocketsphinx.FsgModelAddSilence(f.f, String(word).S(), int32(state), float32(prob))), nil
+}
+
+// AddAlternative adds the transitions for an alternative pronunciation alt of the base word,
+// e.g. hello(2) for hello. Returns the number of transitions added.
+func (f *FSG) AddAlternative(base, alt string) (int, error) {
+	mark := markErrors()
+	n := pocketsphinx.FsgModelAddAlt(f.f, String(base).S(), String(alt).S())
+	if n < 0 {
+		return 0, newError(mark, "AddAlternative", base, ErrUnknownWord)
+	}
+	return int(n), nil
+}
+
+// FSGArc is a transition of a grammar.
+type FSGArc struct {
+	From int
+	To   int
+	// Word is the emitted word, empty for null transitions.
+	Word string
+	// Filler is set for silence and filler words, see FSG.AddSilence().
+	Filler bool
+	// LogProb is the log probability scaled by the language weight, as used by the decoder.
+	LogProb int32
+	// Prob is the probability of the transition.
+	Prob float64
+}
+
+// Arcs lists the transitions leaving the state, the ones emitting words come first.
+func (f *FSG) Arcs(state int) []FSGArc {
+	if f.checkState("Arcs", state) != nil {
+		return nil
+	}
+	lmath := pocketsphinx.FsgModelLogmath(f.f)
+	lw := float64(f.LanguageWeight())
+	var arcs []FSGArc
+	for itor := pocketsphinx.FsgModelArcs(f.f, int32(state)); itor != nil; itor = pocketsphinx.FsgArciterNext(itor) {
+		link := pocketsphinx.FsgArciterGet(itor)
+		if link == nil {
+			continue
+		}
+		link.Deref()
+		arc := FSGArc{
+			From:    int(link.FromState),
+			To:      int(link.ToState),
+			LogProb: link.Logs2prob,
+		}
+		if link.Wid >= 0 {
+			arc.Word = pocketsphinx.FsgModelWordStr(f.f, link.Wid)
+			arc.Filler = pocketsphinx.FsgModelIsFiller(f.f, link.Wid)
+		}
+		if lw > 0 {
+			arc.Prob = pocketsphinx.LogmathExp(lmath, int32(float64(link.Logs2prob)/lw))
+		}
+		arcs = append(arcs, arc)
+	}
+	return arcs
+}
+
+// Save writes the grammar to a file in Sphinx FSG format.
+func (f *FSG) Save(filename string) error {
+	return f.save("Save", filename, pocketsphinx.FsgModelWritefile)
+}
+
+// SaveFSM writes the grammar to a file in AT&T FSM format, see FSG.SaveSymbols()
+// for the symbol table.
+func (f *FSG) SaveFSM(filename string) error {
+	return f.save("SaveFSM", filename, pocketsphinx.FsgModelWritefileFsm)
+}
+
+// SaveSymbols writes the symbol table of the words for the AT&T FSM format.
+func (f *FSG) SaveSymbols(filename string) error {
+	return f.save("SaveSymbols", filename, pocketsphinx.FsgModelWritefileSymtab)
+}
+
+func (f *FSG) save(op, filename string, write func(*pocketsphinx.FsgModel, string)) error {
+	// the writers report no errors, make sure the file can be written beforehand
+	fh, err := os.Create(filename)
+	if err != nil {
+		return &Error{Op: op, Arg: filename, Err: fmt.Errorf("%w: %v", ErrFailed, err)}
+	}
+	fh.Close()
+	write(f.f, String(filename).S())
+	return nil
+}
+
+// WriteTo writes the grammar in Sphinx FSG format.
+func (f *FSG) WriteTo(w io.Writer) (int64, error) {
+	tmp, err := os.CreateTemp("", "sphinx-*.fsg")
+	if err != nil {
+		return 0, &Error{Op: "WriteTo", Err: fmt.Errorf("%w: %v", ErrFailed, err)}
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+	if err := f.save("WriteTo", tmp.Name(), pocketsphinx.FsgModelWritefile); err != nil {
+		return 0, err
+	}
+	data, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return 0, &Error{Op: "WriteTo", Err: fmt.Errorf("%w: %v", ErrFailed, err)}
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// Retain gets a new reference to the grammar owned by the caller, it must be closed
+// when no longer needed.
+func (f *FSG) Retain() *FSG {
+	return ownFSG(pocketsphinx.FsgModelRetain(f.f), f.lmath)
+}
+
+// Close releases the reference to the grammar, it is a no-op for borrowed grammars.
+func (f *FSG) Close() error {
+	f.release()
+	return nil
+}
+
+// Destroy releases the reference to the grammar, returns true if it has been freed.
+//
+// Deprecated: use Close.
+func (f *FSG) Destroy() bool {
+	return f.release()
+}
+
+func (f *FSG) release() bool {
+	if f.f == nil || !f.owned {
+		return false
+	}
+	runtime.SetFinalizer(f, nil)
+	ret := pocketsphinx.FsgModelFree(f.f)
+	f.f = nil
+	if f.lmath != nil {
+		pocketsphinx.LogmathFree(f.lmath)
+		f.lmath = nil
+	}
+	return ret == 0
+}
@@ -0,0 +1,467 @@
+package sphinx
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// G2P converts the spelling of a word to its pronunciation, it is used to add
+// the words missing from the dictionary, see Decoder.EnsureWords().
+type G2P interface {
+	// Pronounce generates the pronunciation of the word, it reports ErrNoPronunciation
+	// if the word cannot be pronounced.
+	Pronounce(word string) (Pronunciation, error)
+}
+
+// G2PFunc adapts a function to the G2P interface.
+type G2PFunc func(word string) (Pronunciation, error)
+
+// Pronounce calls f(word).
+func (f G2PFunc) Pronounce(word string) (Pronunciation, error) {
+	return f(word)
+}
+
+// g2pWindow is the number of letters to the left and to the right of the letter being pronounced.
+type g2pWindow struct {
+	left, right int
+}
+
+// g2pWindows are tried from the widest to the narrowest, the first one having a rule wins.
+var g2pWindows = []g2pWindow{
+	{3, 3}, {2, 3}, {3, 2}, {2, 2}, {1, 2}, {2, 1}, {1, 1}, {0, 1}, {1, 0}, {0, 0},
+}
+
+// g2pIterations is the number of EM passes aligning letters to phones during training.
+const g2pIterations = 5
+
+// g2pMinCount is the number of times a context wider than a single letter must be seen
+// in training to make a rule.
+const g2pMinCount = 3
+
+// RuleG2P is a statistical G2P learning letter to phone rules from a dictionary. Each letter
+// is pronounced as zero, one or two phones depending on the letters around it, the rule
+// of the widest context having one is applied. The contexts wider than a single letter only
+// make rules for the pronunciations seen a few times that the narrower ones get wrong,
+// so the model generalizes rather than memorizes the training dictionary.
+//
+// It is trained with TrainG2P and is safe for concurrent use afterwards.
+type RuleG2P struct {
+	// rules maps a letter context to the phones it is pronounced as, see g2pContext.
+	rules map[string]string
+}
+
+// TrainG2P learns the pronunciation rules from a dictionary, e.g. the one of the acoustic model:
+//
+//	dict, err := sphinx.LoadDictionary("/usr/local/share/pocketsphinx/model/en-us/cmudict-en-us.dict")
+//	...
+//	g2p := sphinx.TrainG2P(dict)
+//
+// The spelling is case insensitive. Words that cannot be aligned to their pronunciations,
+// i.e. having more than two phones per letter, are skipped.
+func TrainG2P(dict *Dictionary) *RuleG2P {
+	// the letters and the chunks of phones are numbered, chunk 0 being no phone at all
+	symbols := &g2pSymbols{ids: map[string]int32{"": 0}, names: []string{""}}
+	var examples []g2pExample
+	for _, word := range dict.Words() {
+		letters := g2pLetters(word)
+		for _, pron := range dict.words[word] {
+			if len(letters) > 0 && len(pron) <= 2*len(letters) {
+				examples = append(examples, symbols.example(letters, pron))
+			}
+		}
+	}
+
+	// learn the probabilities of letters being pronounced as phones with EM,
+	// then take the most likely alignment of every example
+	var lattice g2pLattice
+	model := newG2PAligner(len(symbols.names))
+	for iter := 0; iter < g2pIterations; iter++ {
+		counts := newG2PAligner(len(symbols.names))
+		for i := range examples {
+			model.expect(&examples[i], counts, &lattice)
+		}
+		model = counts
+	}
+	alignments := make([][]int32, len(examples))
+	for i := range examples {
+		alignments[i] = model.align(&examples[i], &lattice)
+	}
+
+	// make the rules from the narrowest to the widest context, one window at a time, a rule
+	// is kept only if it is seen often enough and pronounces its letters better than the
+	// narrower rules already made, so the wide contexts only keep the exceptions
+	g := &RuleG2P{
+		rules: make(map[string]string),
+	}
+	guesses := make([][]int32, len(examples))
+	contexts := make([][]string, len(examples))
+	for i, ex := range examples {
+		guesses[i] = make([]int32, len(ex.letters))
+		for j := range guesses[i] {
+			guesses[i][j] = g2pNoGuess
+		}
+		contexts[i] = make([]string, len(ex.letters))
+	}
+	for k := len(g2pWindows) - 1; k >= 0; k-- {
+		counts := make(map[g2pCount]int)
+		// fallback counts the letters in a context already pronounced right by the narrower rules
+		fallback := make(map[string]int)
+		for i, ex := range examples {
+			for j := range ex.letters {
+				ctx := g2pContext(ex.letters, j, g2pWindows[k])
+				contexts[i][j] = ctx
+				counts[g2pCount{ctx: ctx, chunk: alignments[i][j]}]++
+				if guesses[i][j] == alignments[i][j] {
+					fallback[ctx]++
+				}
+			}
+		}
+		best := make(map[string]g2pCount)
+		for c, n := range counts {
+			b, ok := best[c.ctx]
+			if !ok || n > counts[b] || (n == counts[b] && symbols.names[c.chunk] < symbols.names[b.chunk]) {
+				best[c.ctx] = c
+			}
+		}
+		rules := make(map[string]int32)
+		for ctx, b := range best {
+			// the single letters make the rules of last resort
+			if n := counts[b]; n > fallback[ctx] && (n >= g2pMinCount || k == len(g2pWindows)-1) {
+				rules[ctx] = b.chunk
+				g.rules[ctx] = symbols.names[b.chunk]
+			}
+		}
+		for i := range examples {
+			for j, ctx := range contexts[i] {
+				if chunk, ok := rules[ctx]; ok {
+					guesses[i][j] = chunk
+				}
+			}
+		}
+	}
+	return g
+}
+
+// g2pCount is the key of the number of times a letter context is pronounced as the chunk of phones.
+type g2pCount struct {
+	ctx   string
+	chunk int32
+}
+
+// g2pNoGuess marks the letters no rule made so far applies to.
+const g2pNoGuess = -1
+
+// Pronounce generates the pronunciation of the word. Characters not seen in training are skipped.
+func (g *RuleG2P) Pronounce(word string) (Pronunciation, error) {
+	letters := g2pLetters(word)
+	var pron Pronunciation
+	for i := range letters {
+		for _, w := range g2pWindows {
+			if chunk, ok := g.rules[g2pContext(letters, i, w)]; ok {
+				pron = append(pron, strings.Fields(chunk)...)
+				break
+			}
+		}
+	}
+	if len(pron) == 0 {
+		return nil, &Error{Op: "Pronounce", Arg: word, Err: ErrNoPronunciation}
+	}
+	return pron, nil
+}
+
+// g2pLetters splits the word into lower case letters, dropping the alternative pronunciation number.
+func g2pLetters(word string) []string {
+	var letters []string
+	for _, r := range strings.ToLower(BaseWord(word)) {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		letters = append(letters, string(r))
+	}
+	return letters
+}
+
+// g2pContext builds the key of the letter at i with its neighbours in the window,
+// the word boundaries are marked with #.
+func g2pContext(letters []string, i int, w g2pWindow) string {
+	var b strings.Builder
+	for k := i - w.left; k <= i+w.right; k++ {
+		switch {
+		case k == i:
+			b.WriteString("[" + letters[k] + "]")
+		case k < 0 || k >= len(letters):
+			b.WriteString("#")
+		default:
+			b.WriteString(letters[k])
+		}
+	}
+	return b.String()
+}
+
+// g2pSymbols numbers the letters and the chunks of phones seen in training.
+type g2pSymbols struct {
+	ids   map[string]int32
+	names []string
+}
+
+func (s *g2pSymbols) id(name string) int32 {
+	id, ok := s.ids[name]
+	if !ok {
+		id = int32(len(s.names))
+		s.ids[name] = id
+		s.names = append(s.names, name)
+	}
+	return id
+}
+
+// g2pExample is a word of the training dictionary with one of its pronunciations.
+type g2pExample struct {
+	letters []string
+	ids     []int32
+	// chunks[j][k] is the number of the k phones from j
+	chunks [][3]int32
+}
+
+func (s *g2pSymbols) example(letters []string, phones Pronunciation) g2pExample {
+	ex := g2pExample{
+		letters: letters,
+		ids:     make([]int32, len(letters)),
+		chunks:  make([][3]int32, len(phones)+1),
+	}
+	for i, letter := range letters {
+		ex.ids[i] = s.id(letter)
+	}
+	for j := range ex.chunks {
+		for k := 1; k <= 2 && j+k <= len(phones); k++ {
+			ex.chunks[j][k] = s.id(phones[j : j+k].String())
+		}
+	}
+	return ex
+}
+
+// g2pAligner holds the expected counts of the phones aligned to every letter.
+type g2pAligner struct {
+	// counts is keyed by the letter in the high 32 bits and the chunk in the low ones
+	counts map[uint64]float64
+	// totals is indexed by letter
+	totals []float64
+}
+
+func newG2PAligner(symbols int) *g2pAligner {
+	return &g2pAligner{
+		counts: make(map[uint64]float64),
+		totals: make([]float64, symbols),
+	}
+}
+
+func (a *g2pAligner) add(letter, chunk int32, weight float64) {
+	a.counts[uint64(letter)<<32|uint64(chunk)] += weight
+	a.totals[letter] += weight
+}
+
+// g2pPrior is the probability of a letter being pronounced as zero, one or two phones
+// before training, it is also used to smooth the probabilities of chunks never seen.
+var g2pPrior = [3]float64{0.2, 0.7, 0.1}
+
+// prob gets the probability of the letter being pronounced as the chunk of phones.
+func (a *g2pAligner) prob(letter, chunk int32, phones int) float64 {
+	total := a.totals[letter]
+	if total == 0 {
+		return g2pPrior[phones]
+	}
+	const smoothing = 1e-3
+	return (a.counts[uint64(letter)<<32|uint64(chunk)] + smoothing*g2pPrior[phones]) / (total + smoothing)
+}
+
+// g2pLattice is the scratch space of the aligner, reused from one example to the next.
+// The nodes are indexed by i*(m+1)+j for i letters and j phones aligned, the arcs by
+// node*3+k for the letter i pronounced as the k phones from j.
+type g2pLattice struct {
+	probs []float64
+	fwd   []float64
+	bwd   []float64
+	from  []int8
+}
+
+// reset sizes the lattice for n letters and m phones and computes the probabilities of the arcs.
+func (l *g2pLattice) reset(a *g2pAligner, ex *g2pExample) {
+	n, m := len(ex.ids), len(ex.chunks)-1
+	nodes := (n + 1) * (m + 1)
+	l.probs = g2pResize(l.probs, nodes*3)
+	l.fwd = g2pResize(l.fwd, nodes)
+	l.bwd = g2pResize(l.bwd, nodes)
+	if cap(l.from) < nodes {
+		l.from = make([]int8, nodes)
+	}
+	l.from = l.from[:nodes]
+	for i, letter := range ex.ids {
+		for j := 0; j <= m; j++ {
+			for k := 0; k <= 2 && j+k <= m; k++ {
+				l.probs[(i*(m+1)+j)*3+k] = a.prob(letter, ex.chunks[j][k], k)
+			}
+		}
+	}
+}
+
+func g2pResize(s []float64, n int) []float64 {
+	if cap(s) < n {
+		return make([]float64, n)
+	}
+	s = s[:n]
+	for i := range s {
+		s[i] = 0
+	}
+	return s
+}
+
+// expect adds the expected counts of the alignments of the letters to the phones,
+// computed with the forward-backward algorithm.
+func (a *g2pAligner) expect(ex *g2pExample, counts *g2pAligner, l *g2pLattice) {
+	l.reset(a, ex)
+	n, w := len(ex.ids), len(ex.chunks)
+	m := w - 1
+	probs, fwd, bwd := l.probs, l.fwd, l.bwd
+	fwd[0] = 1
+	for i := 1; i <= n; i++ {
+		for j := 0; j <= m; j++ {
+			var s float64
+			for k := 0; k <= 2 && k <= j; k++ {
+				from := (i-1)*w + j - k
+				s += fwd[from] * probs[from*3+k]
+			}
+			fwd[i*w+j] = s
+		}
+	}
+	total := fwd[n*w+m]
+	if total == 0 || math.IsInf(total, 0) {
+		return
+	}
+	bwd[n*w+m] = 1
+	for i := n - 1; i >= 0; i-- {
+		for j := m; j >= 0; j-- {
+			var s float64
+			for k := 0; k <= 2 && j+k <= m; k++ {
+				s += probs[(i*w+j)*3+k] * bwd[(i+1)*w+j+k]
+			}
+			bwd[i*w+j] = s
+		}
+	}
+	for i, letter := range ex.ids {
+		for j := 0; j <= m; j++ {
+			for k := 0; k <= 2 && j+k <= m; k++ {
+				node := i*w + j
+				if p := fwd[node] * probs[node*3+k] * bwd[(i+1)*w+j+k] / total; p > 0 {
+					counts.add(letter, ex.chunks[j][k], p)
+				}
+			}
+		}
+	}
+}
+
+// align finds the most likely assignment of zero, one or two phones to every letter.
+func (a *g2pAligner) align(ex *g2pExample, l *g2pLattice) []int32 {
+	l.reset(a, ex)
+	n, w := len(ex.ids), len(ex.chunks)
+	m := w - 1
+	best, from := l.fwd, l.from
+	for node := range best {
+		best[node] = math.Inf(-1)
+	}
+	best[0] = 0
+	for i := 1; i <= n; i++ {
+		for j := 0; j <= m; j++ {
+			node := i*w + j
+			for k := 0; k <= 2 && k <= j; k++ {
+				prev := (i-1)*w + j - k
+				if s := best[prev] + math.Log(l.probs[prev*3+k]); s > best[node] {
+					best[node] = s
+					from[node] = int8(k)
+				}
+			}
+		}
+	}
+	aligned := make([]int32, n)
+	for i, j := n, m; i > 0; i-- {
+		k := int(from[i*w+j])
+		aligned[i-1] = ex.chunks[j-k][k]
+		j -= k
+	}
+	return aligned
+}
+
+// SetG2P sets the G2P used to pronounce the words missing from the dictionary, see Decoder.EnsureWords().
+func (d *Decoder) SetG2P(g G2P) {
+	d.g2p = g
+}
+
+// EnsureWords adds the words missing from the dictionary, pronounced with the G2P set with
+// Decoder.SetG2P(), and updates the active search to recognize them. Returns the words added,
+// the words that could not be pronounced or added are reported in a *ValidationError.
+func (d *Decoder) EnsureWords(words ...string) (added []string, err error) {
+	var errs []*Error
+	type entry struct {
+		word   string
+		phones string
+	}
+	var entries []entry
+	seen := make(map[string]bool, len(words))
+	for _, word := range words {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		if _, ok := d.LookupWord(String(word)); ok {
+			continue
+		}
+		if d.g2p == nil {
+			errs = append(errs, &Error{Op: "EnsureWords", Arg: word, Err: ErrNoPronunciation})
+			continue
+		}
+		pron, err := d.g2p.Pronounce(word)
+		if err != nil {
+			if e, ok := err.(*Error); ok {
+				errs = append(errs, e)
+			} else {
+				errs = append(errs, &Error{Op: "EnsureWords", Arg: word, Err: err})
+			}
+			continue
+		}
+		entries = append(entries, entry{word: word, phones: pron.String()})
+	}
+	// ps_add_word is the only way to update the search, it is done along with the last word,
+	// so the phones are checked beforehand to make sure the last word is not rejected
+	if known := d.modelPhones(); known != nil {
+		valid := entries[:0]
+		for _, e := range entries {
+			if phone := unknownPhone(ParsePronunciation(e.phones), known); phone != "" {
+				errs = append(errs, &Error{Op: "EnsureWords", Arg: e.word, Err: fmt.Errorf("%w: %s", ErrBadPhone, phone)})
+				continue
+			}
+			valid = append(valid, e)
+		}
+		entries = valid
+	}
+	for i, e := range entries {
+		if _, err := d.AddWord(String(e.word), String(e.phones), i == len(entries)-1); err != nil {
+			errs = append(errs, err.(*Error))
+			continue
+		}
+		added = append(added, e.word)
+	}
+	if len(errs) > 0 {
+		return added, &ValidationError{Errors: errs}
+	}
+	return added, nil
+}
+
+// unknownPhone gets the first phone of the pronunciation missing from the known ones, if any.
+func unknownPhone(pron Pronunciation, known map[string]bool) string {
+	for _, phone := range pron {
+		if !known[phone] {
+			return phone
+		}
+	}
+	return ""
+}
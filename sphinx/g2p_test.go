@@ -0,0 +1,408 @@
+package sphinx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// g2pTrainingDict is a sample of cmudict-en-us.dict.
+const g2pTrainingDict = `
+about AH B AW T
+after AE F T ER
+again AH G EH N
+back B AE K
+bake B EY K
+ball B AO L
+ban B AE N
+band B AE N D
+bar B AA R
+bark B AA R K
+bat B AE T
+because B IH K AH Z
+bell B EH L
+bend B EH N D
+bet B EH T
+better B EH T ER
+bill B IH L
+bin B IH N
+bind B AY N D
+bit B IH T
+bitter B IH T ER
+black B L AE K
+block B L AA K
+boat B OW T
+bold B OW L D
+book B UH K
+brain B R EY N
+bright B R AY T
+bring B R IH NG
+bug B AH G
+bump B AH M P
+bun B AH N
+butter B AH T ER
+cake K EY K
+call K AO L
+came K EY M
+can K AE N
+car K AA R
+cat K AE T
+chin CH IH N
+chip CH IH P
+chop CH AA P
+city S IH T IY
+clock K L AA K
+coat K OW T
+cold K OW L D
+cook K UH K
+cool K UW L
+could K UH D
+cow K AW
+dark D AA R K
+date D EY T
+day D EY
+deep D IY P
+dip D IH P
+dot D AA T
+drink D R IH NG K
+drop D R AA P
+dug D AH G
+dust D AH S T
+each IY CH
+face F EY S
+fall F AO L
+fame F EY M
+fan F AE N
+far F AA R
+fat F AE T
+father F AA DH ER
+feed F IY D
+feet F IY T
+fight F AY T
+fill F IH L
+fin F IH N
+find F AY N D
+fine F AY N
+first F ER S T
+fit F IH T
+float F L OW T
+fool F UW L
+free F R IY
+fun F AH N
+gain G EY N
+game G EY M
+gate G EY T
+get G EH T
+go G OW
+goat G OW T
+gold G OW L D
+good G UH D
+great G R EY T
+gun G AH N
+hall HH AO L
+hand HH AE N D
+hat HH AE T
+hello HH AH L OW
+hill HH IH L
+hip HH IH P
+hit HH IH T
+hold HH OW L D
+hook HH UH K
+hop HH AA P
+hot HH AA T
+house HH AW S
+how HH AW
+hug HH AH G
+jar JH AA R
+jump JH AH M P
+just JH AH S T
+keep K IY P
+kick K IH K
+kind K AY N D
+king K IH NG
+kit K IH T
+know N OW
+lake L EY K
+land L AE N D
+late L EY T
+lend L EH N D
+let L EH T
+letter L EH T ER
+light L AY T
+line L AY N
+link L IH NG K
+lip L IH P
+little L IH T AH L
+lock L AA K
+look L UH K
+lot L AA T
+lump L AH M P
+main M EY N
+make M EY K
+man M AE N
+mark M AA R K
+mat M AE T
+mate M EY T
+may M EY
+meet M IY T
+mend M EH N D
+met M EH T
+might M AY T
+mill M IH L
+mind M AY N D
+mine M AY N
+moon M UW N
+mop M AA P
+mother M AH DH ER
+mouse M AW S
+much M AH CH
+mug M AH G
+must M AH S T
+name N EY M
+need N IY D
+net N EH T
+night N AY T
+nine N AY N
+noon N UW N
+not N AA T
+now N AW
+number N AH M B ER
+old OW L D
+out AW T
+pack P AE K
+pain P EY N
+pan P AE N
+park P AA R K
+pat P AE T
+pay P EY
+people P IY P AH L
+pet P EH T
+phone F OW N
+pick P IH K
+pill P IH L
+pin P IH N
+pine P AY N
+pink P IH NG K
+pit P IH T
+place P L EY S
+plan P L AE N
+plate P L EY T
+play P L EY
+pool P UW L
+pop P AA P
+pot P AA T
+pump P AH M P
+quick K W IH K
+rack R AE K
+rain R EY N
+ran R AE N
+rat R AE T
+rate R EY T
+rich R IH CH
+right R AY T
+ring R IH NG
+rock R AA K
+rug R AH G
+run R AH N
+rust R AH S T
+sack S AE K
+same S EY M
+sand S AE N D
+sat S AE T
+say S EY
+school S K UW L
+see S IY
+seed S IY D
+sell S EH L
+send S EH N D
+set S EH T
+seven S EH V AH N
+shark SH AA R K
+sheep SH IY P
+sheet SH IY T
+shell SH EH L
+shine SH AY N
+ship SH IH P
+shop SH AA P
+shot SH AA T
+shout SH AW T
+sick S IH K
+sight S AY T
+sing S IH NG
+sink S IH NG K
+sister S IH S T ER
+sit S IH T
+skin S K IH N
+sleep S L IY P
+small S M AO L
+snake S N EY K
+sock S AA K
+sold S OW L D
+soon S UW N
+speech S P IY CH
+speed S P IY D
+spell S P EH L
+spend S P EH N D
+spoon S P UW N
+spot S P AA T
+spring S P R IH NG
+stand S T AE N D
+star S T AA R
+state S T EY T
+stay S T EY
+steep S T IY P
+stick S T IH K
+still S T IH L
+stock S T AA K
+stop S T AA P
+street S T R IY T
+such S AH CH
+sun S AH N
+sweet S W IY T
+table T EY B AH L
+take T EY K
+tall T AO L
+tan T AE N
+tell T EH L
+tend T EH N D
+thank TH AE NG K
+that DH AE T
+then DH EH N
+thick TH IH K
+thin TH IH N
+thing TH IH NG
+think TH IH NG K
+this DH IH S
+three TH R IY
+tick T IH K
+tight T AY T
+tin T IH N
+tip T IH P
+told T OW L D
+took T UH K
+tool T UW L
+top T AA P
+track T R AE K
+train T R EY N
+tree T R IY
+trip T R IH P
+trust T R AH S T
+van V AE N
+wake W EY K
+wall W AO L
+water W AO T ER
+way W EY
+weed W IY D
+well W EH L
+wet W EH T
+when W EH N
+where W EH R
+which W IH CH
+white W AY T
+will W IH L
+win W IH N
+wine W AY N
+wing W IH NG
+winter W IH N T ER
+wit W IH T
+world W ER L D
+year Y IH R
+zero Z IH R OW
+`
+
+// phoneErrors counts the phones substituted, inserted or deleted to turn a pronunciation into the other.
+func phoneErrors(got, expected Pronunciation) int {
+	prev := make([]int, len(expected)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := range got {
+		cur := make([]int, len(expected)+1)
+		cur[0] = i + 1
+		for j := range expected {
+			cur[j+1] = prev[j]
+			if got[i] != expected[j] {
+				cur[j+1]++
+			}
+			if n := prev[j+1] + 1; n < cur[j+1] {
+				cur[j+1] = n
+			}
+			if n := cur[j] + 1; n < cur[j+1] {
+				cur[j+1] = n
+			}
+		}
+		prev = cur
+	}
+	return prev[len(expected)]
+}
+
+func TestRuleG2P(t *testing.T) {
+	dict, err := ReadDictionary(strings.NewReader(g2pTrainingDict))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g2p := TrainG2P(dict)
+	var errs, phones int
+	for _, word := range dict.Words() {
+		expected := dict.Lookup(word)[0]
+		pron, err := g2p.Pronounce(word)
+		if err != nil {
+			t.Errorf("%s: %v", word, err)
+			continue
+		}
+		if upper, err := g2p.Pronounce(strings.ToUpper(word)); err != nil || !upper.Equal(pron) {
+			t.Errorf("%s: got %s, %v in upper case, expected %s", word, upper, err, pron)
+		}
+		errs += phoneErrors(pron, expected)
+		phones += len(expected)
+	}
+	// the rules generalize, so the training words are not all reproduced
+	if accuracy := 1 - float64(errs)/float64(phones); accuracy < 0.9 {
+		t.Errorf("phone accuracy of %.3f on the training words, expected at least 0.9", accuracy)
+	}
+}
+
+func TestRuleG2PHeldOut(t *testing.T) {
+	dict, err := ReadDictionary(strings.NewReader(g2pTrainingDict))
+	if err != nil {
+		t.Fatal(err)
+	}
+	heldOut := []string{"brain", "chop", "gold", "lend", "mate", "park", "pump", "sheet", "thick", "tight"}
+	expected := make(map[string]Pronunciation)
+	for _, word := range heldOut {
+		expected[word] = dict.Lookup(word)[0]
+		dict.Remove(word)
+	}
+	g2p := TrainG2P(dict)
+	for _, word := range heldOut {
+		pron, err := g2p.Pronounce(word)
+		if err != nil {
+			t.Errorf("%s: %v", word, err)
+			continue
+		}
+		if !pron.Equal(expected[word]) {
+			t.Errorf("%s: got %s, expected %s", word, pron, expected[word])
+		}
+	}
+}
+
+func TestRuleG2PUnknown(t *testing.T) {
+	dict, err := ReadDictionary(strings.NewReader(g2pTrainingDict))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g2p := TrainG2P(dict)
+	cases := []struct {
+		word string
+		err  error
+	}{
+		{"", ErrNoPronunciation},
+		{"€", ErrNoPronunciation},
+		{"bait", nil},
+	}
+	for _, tc := range cases {
+		if _, err := g2p.Pronounce(tc.word); !errors.Is(err, tc.err) {
+			t.Errorf("%q: got %v, expected %v", tc.word, err, tc.err)
+		}
+	}
+}
@@ -0,0 +1,478 @@
+package sphinx
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Grammar builds a JSGF grammar from Go values, so the grammars generated from data need no
+// string concatenation. Tokens are escaped as needed, and the structure is checked by
+// Grammar.Validate() before the grammar is rendered:
+//
+//	g := sphinx.NewGrammar("commands")
+//	g.AddRule("action", false, sphinx.Alternative(sphinx.Token("open"), sphinx.Token("close")))
+//	g.AddRule("command", true, sphinx.RuleRef("action"), sphinx.Optional(sphinx.Token("the")), sphinx.Token("door"))
+//	err := dec.SetGrammar("commands", g)
+//
+// A Grammar is not safe for concurrent modification.
+type Grammar struct {
+	name    string
+	imports []string
+	rules   []grammarRule
+}
+
+type grammarRule struct {
+	name   string
+	public bool
+	exp    Expansion
+}
+
+// NewGrammar creates an empty grammar, name is the grammar name, e.g. "commands" or "com.acme.commands".
+func NewGrammar(name string) *Grammar {
+	return &Grammar{
+		name: name,
+	}
+}
+
+// Name gets the grammar name.
+func (g *Grammar) Name() string {
+	return g.name
+}
+
+// Import imports a public rule of another grammar, e.g. "numbers.digit", or all of them, e.g. "numbers.*".
+// The imported grammars are looked up relative to the working directory and in JSGF_PATH.
+func (g *Grammar) Import(name string) {
+	g.imports = append(g.imports, name)
+}
+
+// AddRule adds a rule expanding to the sequence of expansions. Only public rules can be
+// used to start the recognition from, or be imported by other grammars.
+func (g *Grammar) AddRule(name string, public bool, exps ...Expansion) {
+	g.rules = append(g.rules, grammarRule{
+		name:   name,
+		public: public,
+		exp:    Sequence(exps...),
+	})
+}
+
+// Rules lists the names of the rules in the order they were added.
+func (g *Grammar) Rules() []string {
+	names := make([]string, 0, len(g.rules))
+	for _, r := range g.rules {
+		names = append(names, r.name)
+	}
+	return names
+}
+
+// Words lists the distinct tokens of the grammar in the order they appear,
+// not including the tokens of the imported grammars.
+func (g *Grammar) Words() []string {
+	var words []string
+	seen := make(map[string]bool)
+	for _, r := range g.rules {
+		walkExpansion(r.exp, nil, func(e, parent Expansion) {
+			if t, ok := e.(tokenExp); ok && !seen[string(t)] {
+				seen[string(t)] = true
+				words = append(words, string(t))
+			}
+		})
+	}
+	return words
+}
+
+// Expansion is the right hand side of a grammar rule, or a part of it, see Token(),
+// RuleRef(), Sequence(), Alternative(), Optional(), Repeat(), Weight() and Tag().
+type Expansion interface {
+	// writeJSGF renders the expansion, enclosing it in parentheses if it binds looser than prec.
+	writeJSGF(b *strings.Builder, prec int)
+}
+
+// Precedence of the expansions, from the loosest to the tightest binding.
+const (
+	precAlternative = iota
+	precSequence
+	// precUnary is the operand of a tag, optional brackets bind as tight.
+	precUnary
+	// precAtom is the operand of * and +, only tokens, rule references, groups and
+	// other repeats can be repeated.
+	precAtom
+)
+
+type (
+	tokenExp       string
+	ruleRefExp     string
+	sequenceExp    []Expansion
+	alternativeExp []Expansion
+	optionalExp    struct{ exp Expansion }
+	repeatExp      struct{ exp Expansion }
+	weightExp      struct {
+		weight float64
+		exp    Expansion
+	}
+	tagExp struct {
+		exp Expansion
+		tag string
+	}
+)
+
+// Token creates the sequence of the words, each is a single token of the grammar
+// escaped as needed, e.g. Token("turn", "on").
+func Token(words ...string) Expansion {
+	if len(words) == 1 {
+		return tokenExp(words[0])
+	}
+	seq := make(sequenceExp, 0, len(words))
+	for _, word := range words {
+		seq = append(seq, tokenExp(word))
+	}
+	return seq
+}
+
+// RuleRef refers to a rule of the grammar by its name, to an imported rule by its
+// qualified name, e.g. "numbers.digit", or to the special rules NULL and VOID.
+func RuleRef(name string) Expansion {
+	return ruleRefExp(name)
+}
+
+// Sequence matches the expansions one after another.
+func Sequence(exps ...Expansion) Expansion {
+	if len(exps) == 1 {
+		return exps[0]
+	}
+	return sequenceExp(exps)
+}
+
+// Alternative matches any one of the expansions, see Weight() to make some of them more likely.
+func Alternative(exps ...Expansion) Expansion {
+	return alternativeExp(exps)
+}
+
+// Optional matches the sequence of the expansions or nothing.
+func Optional(exps ...Expansion) Expansion {
+	return optionalExp{Sequence(exps...)}
+}
+
+// Repeat matches the sequence of the expansions one or more times,
+// use Optional(Repeat(...)) to match it any number of times.
+func Repeat(exps ...Expansion) Expansion {
+	return repeatExp{Sequence(exps...)}
+}
+
+// Weight sets the relative weight of an item of an Alternative(), if any item has a weight,
+// all of them must have one. Weights are not valid anywhere else.
+func Weight(weight float64, exp Expansion) Expansion {
+	return weightExp{weight: weight, exp: exp}
+}
+
+// Tag attaches the tag to the expansion, e.g. a semantic value for the application.
+func Tag(exp Expansion, tag string) Expansion {
+	return tagExp{exp: exp, tag: tag}
+}
+
+func (t tokenExp) writeJSGF(b *strings.Builder, prec int) {
+	b.WriteString(quoteToken(string(t)))
+}
+
+func (r ruleRefExp) writeJSGF(b *strings.Builder, prec int) {
+	b.WriteString("<" + string(r) + ">")
+}
+
+func (s sequenceExp) writeJSGF(b *strings.Builder, prec int) {
+	writeGroup(b, prec > precSequence, func() {
+		for i, e := range s {
+			if i > 0 {
+				b.WriteString(" ")
+			}
+			e.writeJSGF(b, precSequence)
+		}
+	})
+}
+
+func (a alternativeExp) writeJSGF(b *strings.Builder, prec int) {
+	writeGroup(b, prec > precAlternative, func() {
+		for i, e := range a {
+			if i > 0 {
+				b.WriteString(" | ")
+			}
+			e.writeJSGF(b, precSequence)
+		}
+	})
+}
+
+func (o optionalExp) writeJSGF(b *strings.Builder, prec int) {
+	// [ (x)+ ] is the same as (x)*
+	if r, ok := o.exp.(repeatExp); ok {
+		r.exp.writeJSGF(b, precAtom)
+		b.WriteString("*")
+		return
+	}
+	// [ x ]+ is not accepted by sphinxbase
+	writeGroup(b, prec > precUnary, func() {
+		b.WriteString("[ ")
+		o.exp.writeJSGF(b, precAlternative)
+		b.WriteString(" ]")
+	})
+}
+
+func (r repeatExp) writeJSGF(b *strings.Builder, prec int) {
+	r.exp.writeJSGF(b, precAtom)
+	b.WriteString("+")
+}
+
+func (w weightExp) writeJSGF(b *strings.Builder, prec int) {
+	writeGroup(b, prec > precSequence, func() {
+		b.WriteString("/" + strconv.FormatFloat(w.weight, 'f', -1, 64) + "/ ")
+		w.exp.writeJSGF(b, precSequence)
+	})
+}
+
+func (t tagExp) writeJSGF(b *strings.Builder, prec int) {
+	writeGroup(b, prec > precSequence, func() {
+		t.exp.writeJSGF(b, precUnary)
+		b.WriteString(" {" + escapeTag(t.tag) + "}")
+	})
+}
+
+// writeGroup writes the expansion written by f, in parentheses if paren is set.
+func writeGroup(b *strings.Builder, paren bool, f func()) {
+	if paren {
+		b.WriteString("( ")
+	}
+	f()
+	if paren {
+		b.WriteString(" )")
+	}
+}
+
+// jsgfSpecial lists the characters having a meaning in JSGF, tokens containing them must be quoted.
+const jsgfSpecial = ";=|*+<>()[]{}/\"\\"
+
+// quoteToken quotes the token if it contains whitespace or special characters.
+func quoteToken(token string) string {
+	if token != "" && !strings.ContainsAny(token, jsgfSpecial) && strings.IndexFunc(token, unicode.IsSpace) < 0 {
+		return token
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(token) + `"`
+}
+
+func escapeTag(tag string) string {
+	r := strings.NewReplacer(`\`, `\\`, `{`, `\{`, `}`, `\}`)
+	return r.Replace(tag)
+}
+
+// walkExpansion calls f for the expansion and everything it contains, along with the
+// expansion containing it.
+func walkExpansion(e, parent Expansion, f func(e, parent Expansion)) {
+	f(e, parent)
+	switch e := e.(type) {
+	case sequenceExp:
+		for _, item := range e {
+			walkExpansion(item, e, f)
+		}
+	case alternativeExp:
+		for _, item := range e {
+			walkExpansion(item, e, f)
+		}
+	case optionalExp:
+		walkExpansion(e.exp, e, f)
+	case repeatExp:
+		walkExpansion(e.exp, e, f)
+	case weightExp:
+		walkExpansion(e.exp, e, f)
+	case tagExp:
+		walkExpansion(e.exp, e, f)
+	}
+}
+
+// validJSGFName checks a grammar or rule name, which may be qualified with dots.
+func validJSGFName(name string) bool {
+	if name == "" || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") || strings.Contains(name, "..") {
+		return false
+	}
+	return !strings.ContainsAny(name, jsgfSpecial) && strings.IndexFunc(name, unicode.IsSpace) < 0
+}
+
+// Validate checks the grammar before it is rendered: the grammar, rule and import names,
+// that the referenced rules are defined or imported, that no sequence or alternative is
+// empty, and the use of weights. All problems found are reported in a *ValidationError,
+// each as an *Error with the rule name as Arg and ErrInvalidGrammar.
+func (g *Grammar) Validate() error {
+	var errs []*Error
+	add := func(arg, format string, args ...interface{}) {
+		errs = append(errs, &Error{
+			Op:  "Validate",
+			Arg: arg,
+			Err: fmt.Errorf("%w: %s", ErrInvalidGrammar, fmt.Sprintf(format, args...)),
+		})
+	}
+	if !validJSGFName(g.name) {
+		add(g.name, "invalid grammar name")
+	}
+	imported := make(map[string]bool)
+	for _, name := range g.imports {
+		if !validJSGFName(strings.TrimSuffix(name, ".*")) || !strings.Contains(name, ".") {
+			add(name, "invalid import, expected grammar.rule or grammar.*")
+		}
+		imported[name] = true
+	}
+	defined := make(map[string]bool)
+	for _, r := range g.rules {
+		switch {
+		case !validJSGFName(r.name) || strings.Contains(r.name, "."):
+			add(r.name, "invalid rule name")
+		case r.name == "NULL" || r.name == "VOID":
+			add(r.name, "reserved rule name")
+		case defined[r.name]:
+			add(r.name, "rule defined more than once")
+		}
+		defined[r.name] = true
+	}
+	if len(g.rules) == 0 {
+		add(g.name, "no rules")
+	}
+
+	// resolves tells whether a rule reference can be resolved by sphinxbase
+	resolves := func(ref string) bool {
+		if ref == "NULL" || ref == "VOID" || defined[ref] {
+			return true
+		}
+		i := strings.LastIndex(ref, ".")
+		if i < 0 {
+			// an unqualified imported rule
+			for name := range imported {
+				if strings.HasSuffix(name, "."+ref) {
+					return true
+				}
+			}
+			return hasWildcardImport(g.imports)
+		}
+		grammar, rule := ref[:i], ref[i+1:]
+		return (grammar == g.name && defined[rule]) || imported[ref] || imported[grammar+".*"]
+	}
+	for _, r := range g.rules {
+		walkExpansion(r.exp, nil, func(e, parent Expansion) {
+			switch e := e.(type) {
+			case tokenExp:
+				if e == "" {
+					add(r.name, "empty token")
+				}
+			case ruleRefExp:
+				if !validJSGFName(string(e)) {
+					add(r.name, "invalid rule reference <%s>", e)
+				} else if !resolves(string(e)) {
+					add(r.name, "undefined rule <%s>", e)
+				}
+			case sequenceExp:
+				if len(e) == 0 {
+					add(r.name, "empty sequence")
+				}
+			case alternativeExp:
+				if len(e) == 0 {
+					add(r.name, "empty alternative")
+				}
+				var weighted int
+				for _, item := range e {
+					if _, ok := item.(weightExp); ok {
+						weighted++
+					}
+				}
+				if weighted > 0 && weighted < len(e) {
+					add(r.name, "some items of an alternative have no weight")
+				}
+			case weightExp:
+				// weights are only valid directly inside alternatives
+				if _, ok := parent.(alternativeExp); !ok {
+					add(r.name, "weight outside of an alternative")
+				}
+				if !(e.weight >= 0) || e.weight > 1e300 {
+					add(r.name, "invalid weight %v", e.weight)
+				}
+			case optionalExp, repeatExp, tagExp:
+			case nil:
+				add(r.name, "nil expansion")
+			default:
+				add(r.name, "unsupported expansion %T", e)
+			}
+		})
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: errs}
+}
+
+func hasWildcardImport(imports []string) bool {
+	for _, name := range imports {
+		if strings.HasSuffix(name, ".*") {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteTo validates the grammar and writes it in JSGF format.
+func (g *Grammar) WriteTo(w io.Writer) (int64, error) {
+	if err := g.Validate(); err != nil {
+		return 0, err
+	}
+	n, err := io.WriteString(w, g.String())
+	return int64(n), err
+}
+
+// String renders the grammar in JSGF format, see Grammar.WriteTo() to validate it beforehand.
+func (g *Grammar) String() string {
+	var b strings.Builder
+	b.WriteString("#JSGF V1.0;\n\n")
+	b.WriteString("grammar " + g.name + ";\n\n")
+	for _, name := range g.imports {
+		b.WriteString("import <" + name + ">;\n")
+	}
+	if len(g.imports) > 0 {
+		b.WriteString("\n")
+	}
+	for _, r := range g.rules {
+		if r.public {
+			b.WriteString("public ")
+		}
+		b.WriteString("<" + r.name + "> = ")
+		if r.exp != nil {
+			r.exp.writeJSGF(&b, precAlternative)
+		}
+		b.WriteString(";\n")
+	}
+	return b.String()
+}
+
+// CheckWords reports the words of the grammar missing from the dictionary of the decoder
+// in a *ValidationError, each as an *Error with the word as Arg and ErrUnknownWord.
+func (g *Grammar) CheckWords(d *Decoder) error {
+	var errs []*Error
+	for _, word := range g.Words() {
+		if _, ok := d.LookupWord(String(word)); !ok {
+			errs = append(errs, &Error{Op: "CheckWords", Arg: word, Err: ErrUnknownWord})
+		}
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// SetGrammar associates the grammar search with the provided name, after checking the grammar
+// with Grammar.Validate() and its words with Grammar.CheckWords(). Activate with Decoder.SetSearch()
+//
+// The grammar is compiled starting from the rule set by the -toprule option,
+// or from the first public rule of the grammar.
+func (d *Decoder) SetGrammar(name string, g *Grammar) error {
+	if err := g.Validate(); err != nil {
+		return err
+	}
+	if err := g.CheckWords(d); err != nil {
+		return err
+	}
+	return d.SetJSGFString(name, g.String())
+}
@@ -0,0 +1,67 @@
+package sphinx
+
+import (
+	"strings"
+	"testing"
+)
+
+func renderJSGF(e Expansion) string {
+	var b strings.Builder
+	e.writeJSGF(&b, precAlternative)
+	return b.String()
+}
+
+func TestExpansionUnaryOperators(t *testing.T) {
+	a, b := Token("a"), Token("b")
+	operators := []struct {
+		name string
+		op   func(Expansion) Expansion
+	}{
+		{"Repeat", func(e Expansion) Expansion { return Repeat(e) }},
+		{"Optional(Repeat)", func(e Expansion) Expansion { return Optional(Repeat(e)) }},
+		{"Tag", func(e Expansion) Expansion { return Tag(e, "t") }},
+		{"Optional", func(e Expansion) Expansion { return Optional(e) }},
+	}
+	cases := []struct {
+		name     string
+		exp      Expansion
+		expected [4]string
+	}{
+		{"Token", a, [4]string{"a+", "a*", "a {t}", "[ a ]"}},
+		{"Tokens", Token("a", "b"), [4]string{"( a b )+", "( a b )*", "( a b ) {t}", "[ a b ]"}},
+		{"RuleRef", RuleRef("r"), [4]string{"<r>+", "<r>*", "<r> {t}", "[ <r> ]"}},
+		{"Sequence", Sequence(a, b), [4]string{"( a b )+", "( a b )*", "( a b ) {t}", "[ a b ]"}},
+		{"Alternative", Alternative(a, b), [4]string{"( a | b )+", "( a | b )*", "( a | b ) {t}", "[ a | b ]"}},
+		{"Optional", Optional(a), [4]string{"( [ a ] )+", "( [ a ] )*", "[ a ] {t}", "[ [ a ] ]"}},
+		{"Optional(Repeat)", Optional(Repeat(a)), [4]string{"a*+", "a**", "a* {t}", "[ a* ]"}},
+		{"Repeat", Repeat(a), [4]string{"a++", "a+*", "a+ {t}", "a*"}},
+		{"Tag", Tag(a, "x"), [4]string{"( a {x} )+", "( a {x} )*", "( a {x} ) {t}", "[ a {x} ]"}},
+	}
+	for _, tc := range cases {
+		for i, op := range operators {
+			if got := renderJSGF(op.op(tc.exp)); got != tc.expected[i] {
+				t.Errorf("%s(%s): got %q, expected %q", op.name, tc.name, got, tc.expected[i])
+			}
+		}
+	}
+}
+
+func TestExpansionNesting(t *testing.T) {
+	a, b, c := Token("a"), Token("b"), Token("c")
+	cases := []struct {
+		exp      Expansion
+		expected string
+	}{
+		{Sequence(a, Alternative(b, c)), "a ( b | c )"},
+		{Alternative(Sequence(a, b), c), "a b | c"},
+		{Sequence(a, Tag(b, "t"), c), "a b {t} c"},
+		{Alternative(Tag(a, "t"), b), "a {t} | b"},
+		{Alternative(Weight(2, Sequence(a, b)), Weight(0.5, c)), "/2/ a b | /0.5/ c"},
+		{Token("turn on", "it's"), `"turn on" it's`},
+	}
+	for _, tc := range cases {
+		if got := renderJSGF(tc.exp); got != tc.expected {
+			t.Errorf("got %q, expected %q", got, tc.expected)
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package sphinx
 
 import (
-	"errors"
 	"fmt"
+	"io"
 	"runtime"
+	"sort"
+	"strings"
 
 	"github.com/xlab/pocketsphinx-go/pocketsphinx"
 )
@@ -17,67 +19,91 @@ import (
 
 type JSGF struct {
 	j *pocketsphinx.JSGF
+	// parent keeps the parent grammar alive, as its tables are shared with this one.
+	parent *JSGF
+}
+
+// ownJSGF wraps a grammar owned by the caller.
+func ownJSGF(grammar *pocketsphinx.JSGF, parent *JSGF) *JSGF {
+	j := &JSGF{
+		j:      grammar,
+		parent: parent,
+	}
+	runtime.SetFinalizer(j, func(j *JSGF) {
+		reportLeak("JSGF")
+		j.Close()
+	})
+	return j
 }
 
 // NewJSGFGrammar creates a new JSGF grammar. Parent is optional parent
-// grammar for this one (nil, usually). Rturns new JSGF grammar object, or nil on failure.
+// grammar for this one (nil, usually). Returns new JSGF grammar object, or nil on failure.
+//
+// The parent grammar must not be closed before this one.
 func NewJSGFGrammar(parent *JSGF) (*JSGF, error) {
 	var p *pocketsphinx.JSGF
 	if parent != nil {
 		p = parent.j
 	}
 
+	mark := markErrors()
 	grammar := pocketsphinx.JSGFGrammarNew(p)
 	if grammar != nil {
-		runtime.SetFinalizer(grammar, func(j *pocketsphinx.JSGF) {
-			pocketsphinx.JSGFGrammarFree(j)
-		})
-		return &JSGF{
-			j: grammar,
-		}, nil
-	}
-	err := errors.New("pocketsphinx: failed to create JSGF grammar")
+		return ownJSGF(grammar, parent), nil
+	}
+	err := newError(mark, "NewJSGFGrammar", "", ErrFailed)
 	return nil, err
 }
 
+// JSGFParseFile parses a JSGF grammar from a file, imports are looked up relative to it
+// and in JSGF_PATH. Parent is optional parent grammar, as in NewJSGFGrammar().
 func JSGFParseFile(filename String, parent *JSGF) (*JSGF, error) {
 	var p *pocketsphinx.JSGF
 	if parent != nil {
 		p = parent.j
 	}
 
+	if err := checkFile("JSGFParseFile", filename); err != nil {
+		return nil, err
+	}
+	mark := markErrors()
 	grammar := pocketsphinx.JSGFParseFile(filename.S(), p)
 	if grammar != nil {
-		runtime.SetFinalizer(grammar, func(j *pocketsphinx.JSGF) {
-			pocketsphinx.JSGFGrammarFree(j)
-		})
-		return &JSGF{
-			j: grammar,
-		}, nil
-	}
-	err := fmt.Errorf("pocketsphinx: failed to parse JSGF grammar from file %s", filename)
+		return ownJSGF(grammar, parent), nil
+	}
+	err := newError(mark, "JSGFParseFile", string(filename), ErrFailed)
 	return nil, err
 }
 
+// JSGFParseString parses a JSGF grammar from a string. Parent is optional parent grammar,
+// as in NewJSGFGrammar().
 func JSGFParseString(data String, parent *JSGF) (*JSGF, error) {
 	var p *pocketsphinx.JSGF
 	if parent != nil {
 		p = parent.j
 	}
 
+	mark := markErrors()
 	grammar := pocketsphinx.JSGFParseString(data.S(), p)
 	if grammar != nil {
-		runtime.SetFinalizer(grammar, func(j *pocketsphinx.JSGF) {
-			pocketsphinx.JSGFGrammarFree(j)
-		})
-		return &JSGF{
-			j: grammar,
-		}, nil
-	}
-	err := errors.New("pocketsphinx: failed to parse JSGF grammar")
+		return ownJSGF(grammar, parent), nil
+	}
+	err := newError(mark, "JSGFParseString", "", ErrFailed)
 	return nil, err
 }
 
+// Close frees the grammar.
+func (j *JSGF) Close() error {
+	if j == nil || j.j == nil {
+		return nil
+	}
+	runtime.SetFinalizer(j, nil)
+	pocketsphinx.JSGFGrammarFree(j.j)
+	j.j = nil
+	j.parent = nil
+	return nil
+}
+
 func (j *JSGF) GrammarName() string {
 	if j == nil || j.j == nil {
 		return ""
@@ -85,11 +111,139 @@ func (j *JSGF) GrammarName() string {
 	return pocketsphinx.JSGFGrammarName(j.j)
 }
 
-// func (j *JSGF) Free() {
-// 	if j == nil || j.j == nil {
-// 		return
-// 	}
-// 	pocketsphinx.JSGFGrammarFree(j.j)
-// }
-
+// JSGFRuleIter is the iterator over the rules of a grammar.
+//
+// Deprecated: use JSGF.Rules.
 type JSGFRuleIter pocketsphinx.JSGFRuleIter
+
+// JSGFRule is a rule of a JSGF grammar, borrowed from the grammar and valid while the grammar is open.
+type JSGFRule struct {
+	r *pocketsphinx.JSGFRule
+	// j is the grammar the rule was got from, kept alive while the rule is in use.
+	j *JSGF
+}
+
+// Name gets the fully qualified name of the rule, e.g. "grammar.rule", or "" if the grammar is closed.
+func (r *JSGFRule) Name() string {
+	if r.j.j == nil {
+		return ""
+	}
+	name := pocketsphinx.RawString(pocketsphinx.JSGFRuleName(r.r)).Copy()
+	runtime.KeepAlive(r.j)
+	return strings.TrimSuffix(strings.TrimPrefix(name, "<"), ">")
+}
+
+// Public reports whether the rule is public, only public rules can be imported by other grammars.
+func (r *JSGFRule) Public() bool {
+	if r.j.j == nil {
+		return false
+	}
+	public := pocketsphinx.JSGFRulePublic(r.r) != 0
+	runtime.KeepAlive(r.j)
+	return public
+}
+
+func (r *JSGFRule) String() string {
+	if r.Public() {
+		return "public <" + r.Name() + ">"
+	}
+	return "<" + r.Name() + ">"
+}
+
+// Rules lists the rules of the grammar including the imported ones, sorted by name.
+func (j *JSGF) Rules() []*JSGFRule {
+	if j == nil || j.j == nil {
+		return nil
+	}
+	var rules []*JSGFRule
+	for _, r := range pocketsphinx.JSGFRules(j.j) {
+		rules = append(rules, &JSGFRule{r: r, j: j})
+	}
+	sort.Slice(rules, func(a, b int) bool {
+		return rules[a].Name() < rules[b].Name()
+	})
+	return rules
+}
+
+// Rule gets the rule by its name, which may be qualified with the grammar name and enclosed
+// in angle brackets, e.g. "rule", "grammar.rule" or "<grammar.rule>". Returns nil if there is no such rule.
+func (j *JSGF) Rule(name string) *JSGFRule {
+	if j == nil || j.j == nil {
+		return nil
+	}
+	name = strings.TrimSuffix(strings.TrimPrefix(name, "<"), ">")
+	names := []string{name}
+	if !strings.Contains(name, ".") {
+		names = append(names, j.GrammarName()+"."+name)
+	}
+	for _, name := range names {
+		if r := pocketsphinx.JSGFGetRule(j.j, String(name).S()); r != nil {
+			return &JSGFRule{r: r, j: j}
+		}
+	}
+	return nil
+}
+
+// PublicRule gets the first public rule of the grammar, or nil if there is none.
+func (j *JSGF) PublicRule() *JSGFRule {
+	if j == nil || j.j == nil {
+		return nil
+	}
+	r := pocketsphinx.JSGFGetPublicRule(j.j)
+	if r == nil {
+		return nil
+	}
+	return &JSGFRule{r: r, j: j}
+}
+
+// defaultLogBase is the base of logarithms used by the decoder by default.
+const defaultLogBase = 1.0001
+
+// BuildFSG compiles the grammar starting from rule, or from the first public rule if rule is nil,
+// into a finite state grammar, which must be closed when no longer needed.
+//
+// lmath carries log-math parameters to use for probability calculations, e.g. Decoder.LogMath(),
+// or nil to use the defaults of the decoder. lw is the language weight applied to transition probabilities.
+func (j *JSGF) BuildFSG(rule *JSGFRule, lmath *LogMath, lw float32) (*FSG, error) {
+	if j == nil || j.j == nil {
+		return nil, &Error{Op: "BuildFSG", Err: fmt.Errorf("%w: grammar closed", ErrNoRule)}
+	}
+	if rule == nil {
+		if rule = j.PublicRule(); rule == nil {
+			return nil, &Error{Op: "BuildFSG", Arg: j.GrammarName(), Err: ErrNoRule}
+		}
+	} else if rule.j != j {
+		return nil, &Error{Op: "BuildFSG", Arg: rule.Name(), Err: fmt.Errorf("%w: rule of another grammar", ErrNoRule)}
+	}
+	m := logMathOrDefault(lmath)
+	defer pocketsphinx.LogmathFree(m)
+	mark := markErrors()
+	f := pocketsphinx.JSGFBuildFsg(j.j, rule.r, m, lw)
+	runtime.KeepAlive(j)
+	if f == nil {
+		return nil, newError(mark, "BuildFSG", rule.Name(), ErrFailed)
+	}
+	return ownFSG(f, m), nil
+}
+
+// SaveFSG compiles the grammar starting from rule, see JSGF.BuildFSG(), and writes it to a file
+// in Sphinx FSG format, with the default log-math parameters and unit language weight.
+func (j *JSGF) SaveFSG(rule *JSGFRule, filename string) error {
+	fsg, err := j.BuildFSG(rule, nil, 1)
+	if err != nil {
+		return err
+	}
+	defer fsg.Close()
+	return fsg.Save(filename)
+}
+
+// WriteFSG compiles the grammar starting from rule, see JSGF.BuildFSG(), and writes it
+// in Sphinx FSG format, with the default log-math parameters and unit language weight.
+func (j *JSGF) WriteFSG(rule *JSGFRule, w io.Writer) (int64, error) {
+	fsg, err := j.BuildFSG(rule, nil, 1)
+	if err != nil {
+		return 0, err
+	}
+	defer fsg.Close()
+	return fsg.WriteTo(w)
+}
@@ -1,7 +1,7 @@
 package sphinx
 
 import (
-	"fmt"
+	"runtime"
 
 	"github.com/xlab/pocketsphinx-go/pocketsphinx"
 )
@@ -9,6 +9,21 @@ import (
 // Lattice word graph structure used in bestpath/nbest search.
 type Lattice struct {
 	lat *pocketsphinx.Lattice
+	// owned is set when the wrapper holds its own reference to lat.
+	owned bool
+}
+
+// ownLattice wraps a lattice reference owned by the caller.
+func ownLattice(lat *pocketsphinx.Lattice) *Lattice {
+	l := &Lattice{
+		lat:   lat,
+		owned: true,
+	}
+	runtime.SetFinalizer(l, func(l *Lattice) {
+		reportLeak("Lattice")
+		l.Close()
+	})
+	return l
 }
 
 // LatticeLink represents links between DAG nodes.
@@ -31,15 +46,16 @@ type LatticeNodeIter pocketsphinx.LatnodeIter
 
 // NewLattice reads a lattice from a file on disk.
 func (d *Decoder) NewLattice(filename String) (*Lattice, error) {
+	if err := checkFile("NewLattice", filename); err != nil {
+		return nil, err
+	}
+	mark := markErrors()
 	lat := pocketsphinx.LatticeRead(d.dec, filename.S())
 	if lat == nil {
-		err := fmt.Errorf("sphinx: failed to load lattice from %s", filename)
+		err := newError(mark, "NewLattice", string(filename), ErrFailed)
 		return nil, err
 	}
-	l := &Lattice{
-		lat: lat,
-	}
-	return l, nil
+	return ownLattice(lat), nil
 }
 
 // Lattice returns a retained copy of underlying reference to pocketsphinx.Lattice.
@@ -47,36 +63,59 @@ func (l *Lattice) Lattice() *pocketsphinx.Lattice {
 	return pocketsphinx.LatticeRetain(l.lat)
 }
 
-func (l *Lattice) Retain() {
-	l.lat = pocketsphinx.LatticeRetain(l.lat)
+// Retain gets a new reference to the lattice owned by the caller, it must be closed
+// when no longer needed.
+func (l *Lattice) Retain() *Lattice {
+	return ownLattice(pocketsphinx.LatticeRetain(l.lat))
+}
+
+// Close releases the reference to the lattice, it is a no-op for borrowed lattices.
+func (l *Lattice) Close() error {
+	l.release()
+	return nil
 }
 
+// Destroy releases the reference to the lattice, returns true if the lattice has been freed.
+//
+// Deprecated: use Close.
 func (l *Lattice) Destroy() bool {
-	if l.lat != nil {
-		ret := pocketsphinx.LatticeFree(l.lat)
-		l.lat = nil
-		return ret == 0
+	return l.release()
+}
+
+func (l *Lattice) release() bool {
+	if l.lat == nil || !l.owned {
+		return false
 	}
-	return true
+	runtime.SetFinalizer(l, nil)
+	ret := pocketsphinx.LatticeFree(l.lat)
+	l.lat = nil
+	return ret == 0
 }
 
 // WriteTo writes a lattice to disk.
-func (l *Lattice) WriteTo(filename String) bool {
+func (l *Lattice) WriteTo(filename String) error {
+	mark := markErrors()
 	ret := pocketsphinx.LatticeWrite(l.lat, filename.S())
-	return ret == 0
+	if ret < 0 {
+		return newError(mark, "Lattice.WriteTo", string(filename), ErrFailed)
+	}
+	return nil
 }
 
 // WriteToHTK writes a lattice to disk in HTK format.
-func (l *Lattice) WriteToHTK(filename String) bool {
+func (l *Lattice) WriteToHTK(filename String) error {
+	mark := markErrors()
 	ret := pocketsphinx.LatticeWriteHtk(l.lat, filename.S())
-	return ret == 0
+	if ret < 0 {
+		return newError(mark, "Lattice.WriteToHTK", string(filename), ErrFailed)
+	}
+	return nil
 }
 
 // LogMath gets the log-math computation object for this lattice.
 //
-// The lattice retains ownership of this pointer, so you should not attempt to
-// free it manually. Use LogMath.Retain() if you wish to
-// reuse it elsewhere.
+// The lattice retains ownership of this object, closing it is a no-op.
+// Use LogMath.Retain() if you wish to reuse it elsewhere.
 func (l *Lattice) LogMath() *LogMath {
 	m := pocketsphinx.LatticeGetLogmath(l.lat)
 	return &LogMath{
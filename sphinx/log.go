@@ -0,0 +1,235 @@
+package sphinx
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xlab/pocketsphinx-go/pocketsphinx"
+)
+
+// LogLevel is a severity level of sphinxbase log messages.
+type LogLevel int
+
+// Log levels as declared in sphinxbase/err.h.
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+	LogFatal
+)
+
+// LevelFatal is the slog level used for FATAL messages, after which
+// sphinxbase terminates the process.
+const LevelFatal = slog.Level(12)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogDebug:
+		return "DEBUG"
+	case LogInfo:
+		return "INFO"
+	case LogWarn:
+		return "WARN"
+	case LogError:
+		return "ERROR"
+	case LogFatal:
+		return "FATAL"
+	default:
+		return "LEVEL(" + strconv.Itoa(int(l)) + ")"
+	}
+}
+
+// SlogLevel maps the level to a slog level, FATAL is mapped to LevelFatal.
+func (l LogLevel) SlogLevel() slog.Level {
+	switch l {
+	case LogDebug:
+		return slog.LevelDebug
+	case LogInfo:
+		return slog.LevelInfo
+	case LogWarn:
+		return slog.LevelWarn
+	case LogError:
+		return slog.LevelError
+	default:
+		return LevelFatal
+	}
+}
+
+// LogRecord is a single log message reported by sphinxbase or pocketsphinx.
+type LogRecord struct {
+	Level LogLevel
+	// Msg is the message text without the level and source location prefix.
+	Msg string
+	// File and Line give the source location of the message in the C code, if known.
+	File string
+	Line int
+	// Decoder is the ID of the decoder that was doing the operation which
+	// has caused the message, or zero if it cannot be determined.
+	Decoder uint64
+}
+
+// SetLogFunc routes all log messages from the C libraries to fn, instead of writing them
+// to the file set with LogFileOption or to stderr. Multi-part messages are joined, so fn
+// receives exactly one record per line. Pass nil to restore the default logging.
+//
+// Note that fn is invoked synchronously from within the library calls, so it must not
+// call any methods of this package.
+func SetLogFunc(fn func(r LogRecord)) {
+	if fn == nil {
+		pocketsphinx.SetLogFunc(nil)
+		return
+	}
+	lb := &logBuffer{
+		fn: fn,
+	}
+	pocketsphinx.SetLogFunc(lb.write)
+}
+
+// SetLogHandler routes all log messages from the C libraries to the slog handler h,
+// instead of writing them to the file set with LogFileOption or to stderr.
+// The source location is reported with the "file" and "line" attributes and the decoder ID
+// with the "decoder" attribute, if known. Pass nil to restore the default logging.
+func SetLogHandler(h slog.Handler) {
+	if h == nil {
+		SetLogFunc(nil)
+		return
+	}
+	SetLogFunc(func(r LogRecord) {
+		level := r.Level.SlogLevel()
+		ctx := context.Background()
+		if !h.Enabled(ctx, level) {
+			return
+		}
+		rec := slog.NewRecord(time.Now(), level, r.Msg, 0)
+		if len(r.File) > 0 {
+			rec.AddAttrs(slog.String("file", r.File), slog.Int("line", r.Line))
+		}
+		if r.Decoder > 0 {
+			rec.AddAttrs(slog.Uint64("decoder", r.Decoder))
+		}
+		h.Handle(ctx, rec)
+	})
+}
+
+// logBuffer joins continued messages into complete lines.
+type logBuffer struct {
+	mux     sync.Mutex
+	fn      func(r LogRecord)
+	lvl     pocketsphinx.ErrLvl
+	dec     uint64
+	pending strings.Builder
+}
+
+func (b *logBuffer) write(lvl pocketsphinx.ErrLvl, msg string) bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if lvl != pocketsphinx.ErrInfocont {
+		b.flush()
+		b.lvl = lvl
+	}
+	if b.pending.Len() == 0 {
+		b.dec = currentLogDecoder()
+	}
+	b.pending.WriteString(msg)
+	if strings.HasSuffix(msg, "\n") {
+		b.flush()
+	}
+	return true
+}
+
+func (b *logBuffer) flush() {
+	if b.pending.Len() == 0 {
+		return
+	}
+	r := parseLogLine(b.lvl, strings.TrimRight(b.pending.String(), "\n"))
+	b.pending.Reset()
+	r.Decoder = b.dec
+	b.fn(r)
+}
+
+// logLineRx matches messages formatted by err_msg in sphinxbase/err.c, e.g.
+//
+//	INFO: cmd_ln.c(697): Parsing command line:
+//	ERROR: "dict.c", line 195: Failed to open dictionary file
+var logLineRx = regexp.MustCompile(`^(?s)[A-Z_]+: (?:"([^"]+)", line (\d+)|([^\s(]+)\((\d+)\)): (.*)$`)
+
+func parseLogLine(lvl pocketsphinx.ErrLvl, line string) LogRecord {
+	r := LogRecord{
+		Level: logLevel(lvl),
+		Msg:   line,
+	}
+	if m := logLineRx.FindStringSubmatch(line); m != nil {
+		r.Msg = m[5]
+		if len(m[1]) > 0 {
+			r.File = m[1]
+			r.Line, _ = strconv.Atoi(m[2])
+		} else {
+			r.File = m[3]
+			r.Line, _ = strconv.Atoi(m[4])
+		}
+	}
+	return r
+}
+
+func logLevel(lvl pocketsphinx.ErrLvl) LogLevel {
+	switch lvl {
+	case pocketsphinx.ErrDebug:
+		return LogDebug
+	case pocketsphinx.ErrInfo, pocketsphinx.ErrInfocont:
+		return LogInfo
+	case pocketsphinx.ErrWarn:
+		return LogWarn
+	case pocketsphinx.ErrError:
+		return LogError
+	default:
+		return LogFatal
+	}
+}
+
+var lastDecoderID uint64
+
+func nextDecoderID() uint64 {
+	return atomic.AddUint64(&lastDecoderID, 1)
+}
+
+// logScope tracks which decoder is doing an operation, so messages can be attributed to it.
+// When several decoders are busy at the same time, attribution is not possible.
+var logScope struct {
+	sync.Mutex
+	id     uint64
+	active int
+}
+
+// trackLog marks the decoder as busy until the returned function is called.
+func (d *Decoder) trackLog() func() {
+	logScope.Lock()
+	if logScope.active == 0 {
+		logScope.id = d.id
+	} else if logScope.id != d.id {
+		logScope.id = 0
+	}
+	logScope.active++
+	logScope.Unlock()
+	return func() {
+		logScope.Lock()
+		logScope.active--
+		if logScope.active == 0 {
+			logScope.id = 0
+		}
+		logScope.Unlock()
+	}
+}
+
+func currentLogDecoder() uint64 {
+	logScope.Lock()
+	id := logScope.id
+	logScope.Unlock()
+	return id
+}
@@ -1,6 +1,11 @@
 package sphinx
 
-import "github.com/xlab/pocketsphinx-go/pocketsphinx"
+import (
+	"runtime"
+	"strconv"
+
+	"github.com/xlab/pocketsphinx-go/pocketsphinx"
+)
 
 /*
  * Fast integer logarithmic addition operations.
@@ -39,6 +44,33 @@ import "github.com/xlab/pocketsphinx-go/pocketsphinx"
 // LogMath integer log math computation class.
 type LogMath struct {
 	m *pocketsphinx.Logmath
+	// owned is set when the wrapper holds its own reference to m.
+	owned bool
+}
+
+// ownLogMath wraps a log-math reference owned by the caller.
+func ownLogMath(m *pocketsphinx.Logmath) *LogMath {
+	l := &LogMath{
+		m:     m,
+		owned: true,
+	}
+	runtime.SetFinalizer(l, func(l *LogMath) {
+		reportLeak("LogMath")
+		l.Close()
+	})
+	return l
+}
+
+// NewLogMath creates a log-math object with the given base, e.g. 1.0001 as used by the decoder.
+// shift is the number of bits dropped from the addition table to reduce its size,
+// set useTable to precompute it for faster additions.
+func NewLogMath(base float64, shift int, useTable bool) (*LogMath, error) {
+	mark := markErrors()
+	m := pocketsphinx.LogmathInit(base, int32(shift), b(useTable))
+	if m == nil {
+		return nil, newError(mark, "NewLogMath", strconv.FormatFloat(base, 'g', -1, 64), ErrFailed)
+	}
+	return ownLogMath(m), nil
 }
 
 // LogMath returns a retained copy of underlying reference to pocketsphinx.Logmath.
@@ -47,15 +79,22 @@ func (l *LogMath) LogMath() *pocketsphinx.Logmath {
 }
 
 // WriteTo writes a log table to a file.
-func (l LogMath) WriteTo(filename String) bool {
+func (l LogMath) WriteTo(filename String) error {
+	mark := markErrors()
 	ret := pocketsphinx.LogmathWrite(l.m, filename.S())
-	return ret == 0
+	if ret < 0 {
+		return newError(mark, "LogMath.WriteTo", string(filename), ErrFailed)
+	}
+	return nil
 }
 
 // GetTableShape gets the log table size and dimensions.
-func (l LogMath) GetTableShape() (size, width, shift uint32, ok bool) {
+func (l LogMath) GetTableShape() (size, width, shift uint32, err error) {
+	mark := markErrors()
 	ret := pocketsphinx.LogmathGetTableShape(l.m, &size, &width, &shift)
-	ok = ret == 0
+	if ret < 0 {
+		err = newError(mark, "LogMath.GetTableShape", "", ErrFailed)
+	}
 	return
 }
 
@@ -129,15 +168,31 @@ func (l LogMath) LogFloatToLog10(p float32) float64 {
 	return pocketsphinx.LogmathLogFloatToLog10(l.m, p)
 }
 
+// Retain gets a new reference to the log-math object owned by the caller, it must be closed
+// when no longer needed.
+func (l *LogMath) Retain() *LogMath {
+	return ownLogMath(pocketsphinx.LogmathRetain(l.m))
+}
+
+// Close releases the reference to the log-math object, it is a no-op for borrowed objects.
+func (l *LogMath) Close() error {
+	l.release()
+	return nil
+}
+
+// Destroy releases the reference to the log-math object, returns true if it has been freed.
+//
+// Deprecated: use Close.
 func (l *LogMath) Destroy() bool {
-	if l.m != nil {
-		ret := pocketsphinx.LogmathFree(l.m)
-		l.m = nil
-		return ret == 0
-	}
-	return true
+	return l.release()
 }
 
-func (l *LogMath) Retain() {
-	l.m = pocketsphinx.LogmathRetain(l.m)
+func (l *LogMath) release() bool {
+	if l.m == nil || !l.owned {
+		return false
+	}
+	runtime.SetFinalizer(l, nil)
+	ret := pocketsphinx.LogmathFree(l.m)
+	l.m = nil
+	return ret == 0
 }
@@ -1,13 +1,29 @@
 package sphinx
 
 import (
-	"fmt"
+	"runtime"
 
 	"github.com/xlab/pocketsphinx-go/pocketsphinx"
 )
 
+// MLLR is a speaker-adaptive linear transform of the acoustic model.
 type MLLR struct {
 	m *pocketsphinx.Mllr
+	// owned is set when the wrapper holds its own reference to m.
+	owned bool
+}
+
+// ownMLLR wraps a transform reference owned by the caller.
+func ownMLLR(m *pocketsphinx.Mllr) *MLLR {
+	mllr := &MLLR{
+		m:     m,
+		owned: true,
+	}
+	runtime.SetFinalizer(mllr, func(m *MLLR) {
+		reportLeak("MLLR")
+		m.Close()
+	})
+	return mllr
 }
 
 // MLLR returns a retained copy of underlying reference to pocketsphinx.Mllr.
@@ -15,29 +31,46 @@ func (m *MLLR) MLLR() *pocketsphinx.Mllr {
 	return pocketsphinx.MllrRetain(m.m)
 }
 
-func (m *MLLR) Retain() {
-	m.m = pocketsphinx.MllrRetain(m.m)
+// Retain gets a new reference to the transform owned by the caller, it must be closed
+// when no longer needed.
+func (m *MLLR) Retain() *MLLR {
+	return ownMLLR(pocketsphinx.MllrRetain(m.m))
+}
+
+// Close releases the reference to the transform, it is a no-op for borrowed transforms.
+func (m *MLLR) Close() error {
+	m.release()
+	return nil
 }
 
+// Destroy releases the reference to the transform, returns true if it has been freed.
+//
+// Deprecated: use Close.
 func (m *MLLR) Destroy() bool {
-	if m.m != nil {
-		ret := pocketsphinx.MllrFree(m.m)
-		m.m = nil
-		return ret == 0
+	return m.release()
+}
+
+func (m *MLLR) release() bool {
+	if m.m == nil || !m.owned {
+		return false
 	}
-	return true
+	runtime.SetFinalizer(m, nil)
+	ret := pocketsphinx.MllrFree(m.m)
+	m.m = nil
+	return ret == 0
 }
 
 // NewMLLR reads a speaker-adaptive linear transform from a file (mllr_matrix).
 // See http://cmusphinx.sourceforge.net/wiki/tutorialadapt for details.
 func NewMLLR(filename String) (*MLLR, error) {
+	if err := checkFile("NewMLLR", filename); err != nil {
+		return nil, err
+	}
+	mark := markErrors()
 	m := pocketsphinx.MllrRead(filename.S())
 	if m == nil {
-		err := fmt.Errorf("sphinx: failed to load MLLR transform matrix from %s", filename)
+		err := newError(mark, "NewMLLR", string(filename), ErrFailed)
 		return nil, err
 	}
-	mllr := &MLLR{
-		m: m,
-	}
-	return mllr, nil
+	return ownMLLR(m), nil
 }
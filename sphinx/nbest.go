@@ -0,0 +1,37 @@
+package sphinx
+
+import "github.com/xlab/pocketsphinx-go/pocketsphinx"
+
+// Hypothesis represents a single entry of the N-best hypothesis list.
+type Hypothesis struct {
+	// Text is the hypothesis string.
+	Text string
+	// Score is the path score for this hypothesis.
+	Score int32
+	// Segments is the word segmentation of this hypothesis.
+	Segments []Segment
+}
+
+// NBest gets up to n best hypotheses for the current utterance, ordered from the best one.
+// If n <= 0, all the available hypotheses are returned. Returns nil if no hypothesis
+// is available for this utterance.
+//
+// N-best search is done over the word lattice, so it requires a search module that
+// produces one, i.e. an N-Gram search with BestpathOption or FwdFlatOption enabled.
+func (d *Decoder) NBest(n int) []Hypothesis {
+	frate := d.frameRate()
+	var hyps []Hypothesis
+	nbest := pocketsphinx.GetNbest(d.dec)
+	for nbest != nil {
+		if n > 0 && len(hyps) == n {
+			pocketsphinx.NbestFree(nbest)
+			break
+		}
+		var h Hypothesis
+		h.Text = pocketsphinx.RawString(pocketsphinx.NbestHyp(nbest, &h.Score)).Copy()
+		h.Segments = collectSegments(pocketsphinx.NbestSeg(nbest), frate)
+		hyps = append(hyps, h)
+		nbest = pocketsphinx.NbestNext(nbest)
+	}
+	return hyps
+}
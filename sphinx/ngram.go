@@ -1,7 +1,7 @@
 package sphinx
 
 import (
-	"fmt"
+	"runtime"
 
 	"github.com/xlab/pocketsphinx-go/pocketsphinx"
 )
@@ -9,6 +9,21 @@ import (
 // NGramModel is a type representing an N-Gram based language model.
 type NGramModel struct {
 	n *pocketsphinx.NgramModel
+	// owned is set when the wrapper holds its own reference to n.
+	owned bool
+}
+
+// ownNGramModel wraps a language model reference owned by the caller.
+func ownNGramModel(n *pocketsphinx.NgramModel) *NGramModel {
+	m := &NGramModel{
+		n:     n,
+		owned: true,
+	}
+	runtime.SetFinalizer(m, func(m *NGramModel) {
+		reportLeak("NGramModel")
+		m.Close()
+	})
+	return m
 }
 
 // NGramModel returns a retained copy of underlying reference to pocketsphinx.NgramModel.
@@ -32,22 +47,22 @@ func (n *NGramOptions) CommandLn() *pocketsphinx.CommandLn {
 	return n.evaluated
 }
 
-func (n *NGramOptions) Destroy() bool {
-	// TODO: not sure if the CommandLn is retained by us
-	// after passing to the C land.
-	//
-	// if n.evaluated != nil {
-	// 	n.evaluated.Free()
-	// 	n.evaluated = nil
-	// }
-	// return true
+// Close releases the command-line argument set evaluated by NGramOptions.CommandLn().
+func (n *NGramOptions) Close() error {
+	n.Destroy()
+	return nil
+}
 
+// Destroy releases the evaluated command-line argument set, returns true if it has been freed.
+//
+// Deprecated: use Close.
+func (n *NGramOptions) Destroy() bool {
 	if n.evaluated != nil {
 		ret := pocketsphinx.CommandLnFreeR(n.evaluated)
 		n.evaluated = nil
 		return ret == 0
 	}
-	return true
+	return false
 }
 
 // MMap options sets whether to use memory-mapped I/O.
@@ -77,46 +92,62 @@ func (n *NGramOptions) WordInsertionPenalty(v float32) {
 // NewNGramModel reads an N-Gram model from a file on disk.
 //
 // lmath carries log-math parameters to use for probability
-// calculations. Ownership of this object is assumed by
-// the newly created NGramModel, and you should not
-// attempt to free it manually. If you wish to reuse it
-// elsewhere, you must retain it with LogMath.Retain().
+// calculations. The model takes its own reference to lmath, so it is
+// safe to close it afterwards.
 func NewNGramModel(fileName String, fileType NGramFileType,
 	lmath *LogMath, opt ...NGramOptions) (*NGramModel, error) {
+	if err := checkFile("NewNGramModel", fileName); err != nil {
+		return nil, err
+	}
 	ftype := (pocketsphinx.NgramFileType)(fileType)
+	var config *pocketsphinx.CommandLn
 	if len(opt) > 0 {
-		m := pocketsphinx.NgramModelRead(opt[0].CommandLn(), fileName.S(), ftype, lmath.m)
-		if m == nil {
-			err := fmt.Errorf("sphinx: failed to load n-gram model from %s", fileName)
-			return nil, err
+		// the options are only read while loading the model, the argument set evaluated here
+		// is freed afterwards, the one evaluated by the caller stays owned by the caller
+		o := opt[0]
+		if o.evaluated == nil {
+			defer o.Close()
 		}
-		ngram := &NGramModel{
-			n: m,
-		}
-		return ngram, nil
+		config = o.CommandLn()
 	}
-	m := pocketsphinx.NgramModelRead(nil, fileName.S(), ftype, lmath.m)
+	mark := markErrors()
+	// the model assumes ownership of the reference passed in
+	m := pocketsphinx.NgramModelRead(config, fileName.S(), ftype, pocketsphinx.LogmathRetain(lmath.m))
 	if m == nil {
-		err := fmt.Errorf("sphinx: failed to load n-gram model from %s", fileName)
+		pocketsphinx.LogmathFree(lmath.m)
+		err := newError(mark, "NewNGramModel", string(fileName), ErrFailed)
 		return nil, err
 	}
-	ngram := &NGramModel{
-		n: m,
-	}
-	return ngram, nil
+	return ownNGramModel(m), nil
+}
+
+// Retain gets a new reference to the model owned by the caller, it must be closed
+// when no longer needed.
+func (n *NGramModel) Retain() *NGramModel {
+	return ownNGramModel(pocketsphinx.NgramModelRetain(n.n))
 }
 
+// Close releases the reference to the model, it is a no-op for borrowed models.
+func (n *NGramModel) Close() error {
+	n.release()
+	return nil
+}
+
+// Destroy releases the reference to the model, returns true if it has been freed.
+//
+// Deprecated: use Close.
 func (n *NGramModel) Destroy() bool {
-	if n.n != nil {
-		ret := pocketsphinx.NgramModelFree(n.n)
-		n.n = nil
-		return ret == 0
-	}
-	return true
+	return n.release()
 }
 
-func (n *NGramModel) Retain() {
-	n.n = pocketsphinx.NgramModelRetain(n.n)
+func (n *NGramModel) release() bool {
+	if n.n == nil || !n.owned {
+		return false
+	}
+	runtime.SetFinalizer(n, nil)
+	ret := pocketsphinx.NgramModelFree(n.n)
+	n.n = nil
+	return ret == 0
 }
 
 // NgramFileType as declared in sphinxbase/ngram_model.h:81
@@ -147,15 +178,23 @@ const (
 //
 // WARNING: This is not Unicode aware, so any non-ASCII characters
 // will not be converted.
-func (n *NGramModel) CaseFold(c NGramCase) bool {
+func (n *NGramModel) CaseFold(c NGramCase) error {
+	mark := markErrors()
 	ret := pocketsphinx.NgramModelCasefold(n.n, int32(c))
-	return ret == 0
+	if ret < 0 {
+		return newError(mark, "NGramModel.CaseFold", "", ErrFailed)
+	}
+	return nil
 }
 
 // WriteTo writes an N-Gram model to disk.
-func (n *NGramModel) WriteTo(filename String, format NGramFileType) bool {
+func (n *NGramModel) WriteTo(filename String, format NGramFileType) error {
+	mark := markErrors()
 	ret := pocketsphinx.NgramModelWrite(n.n, filename.S(), (pocketsphinx.NgramFileType)(format))
-	return ret == 0
+	if ret < 0 {
+		return newError(mark, "NGramModel.WriteTo", string(filename), ErrFailed)
+	}
+	return nil
 }
 
 // ApplyWeights applies a language weight and insertion penalty weight to a
@@ -167,9 +206,13 @@ func (n *NGramModel) WriteTo(filename String, format NGramFileType) bool {
 // N-Gram probability estimate.
 //
 // To remove all weighting, call NGramModel.ApplyWeights(1.0, 1.0).
-func (n *NGramModel) ApplyWeights(langWeight, insertionPenalty float32) bool {
+func (n *NGramModel) ApplyWeights(langWeight, insertionPenalty float32) error {
+	mark := markErrors()
 	ret := pocketsphinx.NgramModelApplyWeights(n.n, langWeight, insertionPenalty)
-	return ret == 0
+	if ret < 0 {
+		return newError(mark, "NGramModel.ApplyWeights", "", ErrFailed)
+	}
+	return nil
 }
 
 // Weights gets the current language weight from a language model
@@ -289,9 +332,13 @@ func (n *NGramModel) Counts() []uint32 {
 // The semantics of this are not particularly well-defined for
 // model sets, and may be subject to change. Currently this will add
 // the word to all of the submodels
-func (n *NGramModel) AddWord(word String, weight float32) int32 {
+func (n *NGramModel) AddWord(word String, weight float32) (int32, error) {
+	mark := markErrors()
 	id := pocketsphinx.NgramModelAddWord(n.n, word.S(), weight)
-	return id
+	if id == NGgramInvalidWordID {
+		return id, newError(mark, "NGramModel.AddWord", string(word), ErrFailed)
+	}
+	return id, nil
 }
 
 // ReadClassDef reads a class definition file and add classes to a language model.
@@ -303,9 +350,16 @@ func (n *NGramModel) AddWord(word String, weight float32) int32 {
 // as any words in the general unigram distribution. The convention
 // is to suffix them with ":class_tag", where class_tag is the class
 // tag minus the enclosing square brackets.
-func (n *NGramModel) ReadClassDef(filename String) bool {
+func (n *NGramModel) ReadClassDef(filename String) error {
+	if err := checkFile("NGramModel.ReadClassDef", filename); err != nil {
+		return err
+	}
+	mark := markErrors()
 	ret := pocketsphinx.NgramModelReadClassdef(n.n, filename.S())
-	return ret == 0
+	if ret < 0 {
+		return newError(mark, "NGramModel.ReadClassDef", string(filename), ErrFailed)
+	}
+	return nil
 }
 
 // Add a new class to a language model.
@@ -313,16 +367,24 @@ func (n *NGramModel) ReadClassDef(filename String) bool {
 // If className already exists in the unigram set for NGramModel,
 // then it will be converted to a class tag, and weight will be ignored.
 // Otherwise, a new unigram will be created as in NGramModel.AddWord().
-func (n *NGramModel) AddClass(className String, weight float32, words Strings, weights []float32) bool {
+func (n *NGramModel) AddClass(className String, weight float32, words Strings, weights []float32) error {
+	mark := markErrors()
 	ret := pocketsphinx.NgramModelAddClass(n.n, className.S(), weight, words.B(), weights, int32(len(words)))
-	return ret == 0
+	if ret < 0 {
+		return newError(mark, "NGramModel.AddClass", string(className), ErrFailed)
+	}
+	return nil
 }
 
 // AddClassWord adds a word to a class in a language model with a weight
 // of this word relative to the within-class uniform distribution. Returns word ID.
-func (n *NGramModel) AddClassWord(className, word String, weight float32) int32 {
+func (n *NGramModel) AddClassWord(className, word String, weight float32) (int32, error) {
+	mark := markErrors()
 	id := pocketsphinx.NgramModelAddClassWord(n.n, className.S(), word.S(), weight)
-	return id
+	if id == NGgramInvalidWordID {
+		return id, newError(mark, "NGramModel.AddClassWord", string(word), ErrFailed)
+	}
+	return id, nil
 }
 
 // TODO: implement model sets
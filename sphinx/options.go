@@ -0,0 +1,128 @@
+package sphinx
+
+import (
+	"fmt"
+
+	"github.com/xlab/pocketsphinx-go/pocketsphinx"
+)
+
+// OptionType is the type of values of a configuration option.
+type OptionType int
+
+const (
+	// OptionInteger is an option of int values.
+	OptionInteger OptionType = iota + 1
+	// OptionFloat is an option of float64 values.
+	OptionFloat
+	// OptionBool is an option of bool values.
+	OptionBool
+	// OptionString is an option of string values.
+	OptionString
+	// OptionStringList is an option of string list values, pocketsphinx has none currently.
+	OptionStringList
+)
+
+func optionType(kind int32) OptionType {
+	switch kind {
+	case pocketsphinx.ArgInteger:
+		return OptionInteger
+	case pocketsphinx.ArgFloating:
+		return OptionFloat
+	case pocketsphinx.ArgBoolean:
+		return OptionBool
+	case pocketsphinx.ArgString:
+		return OptionString
+	default:
+		return OptionStringList
+	}
+}
+
+func (t OptionType) String() string {
+	switch t {
+	case OptionInteger:
+		return "integer"
+	case OptionFloat:
+		return "floating point"
+	case OptionBool:
+		return "boolean"
+	case OptionString:
+		return "string"
+	case OptionStringList:
+		return "string list"
+	default:
+		return fmt.Sprintf("OptionType(%d)", int(t))
+	}
+}
+
+// OptionInfo describes a configuration option supported by the decoder.
+type OptionInfo struct {
+	// Name is the name of the option with the leading dash, e.g. "-beam".
+	Name string
+	Type OptionType
+	// Required is true if the option must be set.
+	Required bool
+	// Default is the default value as written on the command line, empty if there is none.
+	Default string
+	// Doc is the description of the option.
+	Doc string
+}
+
+func (a *argDef) info() OptionInfo {
+	return OptionInfo{
+		Name:     a.Name,
+		Type:     optionType(a.kind()),
+		Required: a.Type&pocketsphinx.ArgRequired != 0,
+		Default:  a.Default,
+		Doc:      a.Doc,
+	}
+}
+
+// Options lists all options supported by the decoder, as defined by pocketsphinx.
+func Options() []OptionInfo {
+	defs := argDefs()
+	infos := make([]OptionInfo, 0, len(defs))
+	for i := range defs {
+		infos = append(infos, defs[i].info())
+	}
+	return infos
+}
+
+// LookupOption gets the description of the option, the leading dash of the name is optional.
+func LookupOption(name string) (OptionInfo, bool) {
+	def, ok := lookupArg(name)
+	if !ok {
+		return OptionInfo{}, false
+	}
+	return def.info(), true
+}
+
+// OptionValue is the set of Go types of option values.
+type OptionValue interface {
+	int | float64 | bool | string
+}
+
+// SetOption sets the option to v, which must have the Go type matching the option type.
+// The leading dash of the name is optional.
+func SetOption[T OptionValue](c *Config, name string, v T) error {
+	if def, ok := lookupArg(name); ok {
+		if err := checkOptionValue(String(def.Name), v); err != nil {
+			return &Error{Op: "SetOption", Arg: name, Err: err}
+		}
+	}
+	if err := c.Set(name, v); err != nil {
+		err.(*Error).Op = "SetOption"
+		return err
+	}
+	return nil
+}
+
+// TypedOption sets the option to v, see SetOption. An unknown name or a value of a type
+// not matching the option is ignored and reported by Config.Validate().
+func TypedOption[T OptionValue](name string, v T) Option {
+	return func(c *Config) {
+		if def, ok := lookupArg(name); ok {
+			name = def.Name
+		}
+		c.opt[String(name)] = v
+	}
+}
@@ -0,0 +1,209 @@
+package sphinx
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/xlab/pocketsphinx-go/pocketsphinx"
+)
+
+// PitchParams tunes the YIN pitch estimation.
+type PitchParams struct {
+	// VoicingThreshold is the highest normalized difference, i.e. one minus the
+	// confidence, of a voiced frame.
+	VoicingThreshold float32
+	// SearchRange is the fraction of the best period around which a better
+	// local minimum is searched for.
+	SearchRange float32
+	// SmoothWindow is the number of frames on each side of a frame used to smooth
+	// the estimates, it delays the estimates by as many frames.
+	SmoothWindow int
+}
+
+// DefaultPitchParams are the parameters used by the sphinx_pitch tool.
+var DefaultPitchParams = PitchParams{
+	VoicingThreshold: 0.1,
+	SearchRange:      0.2,
+	SmoothWindow:     2,
+}
+
+// Pitch is the pitch estimate of a frame.
+type Pitch struct {
+	// Frame is the frame index, the same as the decoder uses for the same audio.
+	Frame int32
+	// Time is the stream-relative time when the frame starts.
+	Time time.Duration
+	// F0 is the fundamental frequency in Hz, 0 for unvoiced frames.
+	F0 float32
+	// Confidence is the probability of voicing, from 0 to 1.
+	Confidence float32
+}
+
+// Voiced reports whether the frame is voiced, i.e. F0 is known.
+func (p Pitch) Voiced() bool {
+	return p.F0 > 0
+}
+
+// PitchTrack is a sequence of pitch estimates ordered by frame.
+type PitchTrack []Pitch
+
+// Frames gets the estimates of the frames from start to end inclusive.
+func (t PitchTrack) Frames(start, end int32) PitchTrack {
+	from := 0
+	for from < len(t) && t[from].Frame < start {
+		from++
+	}
+	to := from
+	for to < len(t) && t[to].Frame <= end {
+		to++
+	}
+	return t[from:to]
+}
+
+// Segment gets the estimates of the frames of a word segment, see Decoder.Segments().
+func (t PitchTrack) Segment(seg Segment) PitchTrack {
+	return t.Frames(seg.StartFrame, seg.EndFrame)
+}
+
+// PitchTracker estimates the pitch of speech with the YIN algorithm, in the frames of the
+// decoder, so the estimates can be attached to word segments. It keeps the state of the
+// stream and is not safe for concurrent use.
+type PitchTracker struct {
+	pe         *pocketsphinx.Yin
+	params     PitchParams
+	sampleRate float32
+	frameRate  int32
+	frameSize  int
+	frameShift int
+	// buf holds the samples not processed yet.
+	buf []int16
+	// written and read count the frames fed to the estimator and read from it.
+	written, read int32
+}
+
+// NewPitchTracker creates a pitch tracker with the sample rate, frame rate and window length
+// set by the -samprate, -frate and -wlen options, so its frames match the ones of the decoder
+// with the same configuration. Pass nil for the default configuration or params.
+//
+// The frame indices match the ones of a decoder started at the same point of the audio
+// if its silence removal is disabled (-remove_silence no), otherwise silent frames are
+// dropped by the decoder.
+func NewPitchTracker(cfg *Config, params *PitchParams) (*PitchTracker, error) {
+	if cfg == nil {
+		cfg = NewConfig()
+		defer cfg.Close()
+	}
+	if params == nil {
+		params = &DefaultPitchParams
+	}
+	rate, _ := cfg.GetFloat("samprate")
+	frate, _ := cfg.GetInt("frate")
+	wlen, _ := cfg.GetFloat("wlen")
+	if rate <= 0 {
+		return nil, &Error{Op: "NewPitchTracker", Arg: fmt.Sprint(rate), Err: ErrBadSampleRate}
+	}
+	if frate <= 0 || wlen <= 0 {
+		return nil, &Error{Op: "NewPitchTracker", Err: fmt.Errorf("%w: frame rate %d, window length %v", ErrInvalidValue, frate, wlen)}
+	}
+	t := &PitchTracker{
+		params:     *params,
+		sampleRate: float32(rate),
+		frameRate:  int32(frate),
+		// rounded as the front-end does
+		frameSize:  int(wlen*rate + 0.5),
+		frameShift: int(rate/float64(frate) + 0.5),
+	}
+	mark := markErrors()
+	t.pe = pocketsphinx.YinInit(int32(t.frameSize), params.VoicingThreshold, params.SearchRange, int32(params.SmoothWindow))
+	if t.pe == nil {
+		return nil, newError(mark, "NewPitchTracker", "", ErrFailed)
+	}
+	runtime.SetFinalizer(t, func(t *PitchTracker) {
+		reportLeak("PitchTracker")
+		t.Close()
+	})
+	return t, nil
+}
+
+// FrameSize gets the number of samples in a frame, and between the starts of the frames.
+func (t *PitchTracker) FrameSize() (frameSize, frameShift int) {
+	return t.frameSize, t.frameShift
+}
+
+// Start starts processing of an utterance, the frames are counted from 0.
+func (t *PitchTracker) Start() {
+	pocketsphinx.YinStart(t.pe)
+	t.buf = t.buf[:0]
+	t.written, t.read = 0, 0
+}
+
+// Process feeds the samples to the tracker, returns the estimates available so far.
+// The estimates lag behind the input by PitchParams.SmoothWindow frames.
+func (t *PitchTracker) Process(samples []int16) PitchTrack {
+	t.buf = append(t.buf, samples...)
+	var track PitchTrack
+	var pos int
+	for ; pos+t.frameSize <= len(t.buf); pos += t.frameShift {
+		pocketsphinx.YinWrite(t.pe, t.buf[pos:pos+t.frameSize])
+		t.written++
+		track = t.readAll(track)
+	}
+	t.buf = append(t.buf[:0], t.buf[pos:]...)
+	return track
+}
+
+// End finishes the utterance, returns the remaining estimates. The samples short of a frame
+// are padded with zeros as the front-end does, so the last frame of the decoder has an estimate too.
+func (t *PitchTracker) End() PitchTrack {
+	if len(t.buf) > 0 {
+		frame := make([]int16, t.frameSize)
+		copy(frame, t.buf)
+		pocketsphinx.YinWrite(t.pe, frame)
+		t.written++
+	}
+	pocketsphinx.YinEnd(t.pe)
+	t.buf = t.buf[:0]
+	return t.readAll(nil)
+}
+
+// ProcessUtt estimates the pitch of a whole utterance at once.
+func (t *PitchTracker) ProcessUtt(samples []int16) PitchTrack {
+	t.Start()
+	track := t.Process(samples)
+	return append(track, t.End()...)
+}
+
+func (t *PitchTracker) readAll(track PitchTrack) PitchTrack {
+	for t.read < t.written {
+		period, diff, ok := pocketsphinx.YinRead(t.pe)
+		if !ok {
+			break
+		}
+		p := Pitch{
+			Frame: t.read,
+			Time:  framesToDuration(t.read, t.frameRate),
+		}
+		// the difference may exceed 1 in Q15 for frames that are not periodic at all
+		if diff < 32768 {
+			p.Confidence = 1 - float32(diff)/32768
+		}
+		if period > 0 && float32(diff) <= t.params.VoicingThreshold*32768 {
+			p.F0 = t.sampleRate / float32(period)
+		}
+		track = append(track, p)
+		t.read++
+	}
+	return track
+}
+
+// Close frees the pitch tracker.
+func (t *PitchTracker) Close() error {
+	if t.pe == nil {
+		return nil
+	}
+	runtime.SetFinalizer(t, nil)
+	pocketsphinx.YinFree(t.pe)
+	t.pe = nil
+	return nil
+}
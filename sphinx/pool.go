@@ -0,0 +1,220 @@
+package sphinx
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// PoolStats reports the utilisation of a DecoderPool.
+type PoolStats struct {
+	// Size is the number of decoders in the pool.
+	Size int
+	// InUse is the number of decoders currently acquired.
+	InUse int
+	// Waiting is the number of callers blocked in Acquire.
+	Waiting int
+	// Acquired is the total number of successful Acquire calls.
+	Acquired uint64
+	// WaitTime is the total time spent by callers waiting for a decoder.
+	WaitTime time.Duration
+	// BusyTime is the total time the decoders have been lent out, including the current loans.
+	BusyTime time.Duration
+	// Utilization is the share of the decoder time lent out since the pool was created, from 0 to 1.
+	Utilization float64
+}
+
+// DecoderPool lends out decoders built from the same configuration, so a number of
+// goroutines can decode concurrently without loading the models for each of them.
+// It is safe for concurrent use.
+type DecoderPool struct {
+	free    chan *Decoder
+	done    chan struct{}
+	created time.Time
+
+	mux      sync.Mutex
+	closed   bool
+	decoders map[*Decoder]*pooledDecoder
+	stats    PoolStats
+}
+
+// pooledDecoder keeps the state to restore the decoder to when it is released.
+type pooledDecoder struct {
+	search   string
+	searches map[string]bool
+	acquired time.Time
+	inUse    bool
+}
+
+// NewDecoderPool creates size decoders from cfg, it fails if any of them cannot be initialized.
+// Every decoder gets its own copy of the options, so reconfiguring one of them does not affect
+// the others. The pool takes ownership of cfg and closes it.
+func NewDecoderPool(cfg *Config, size int) (*DecoderPool, error) {
+	if size < 1 {
+		size = 1
+	}
+	if cfg == nil {
+		cfg = NewConfig()
+	}
+	values := cfg.Values()
+	cfg.Close()
+	p := &DecoderPool{
+		free:     make(chan *Decoder, size),
+		done:     make(chan struct{}),
+		decoders: make(map[*Decoder]*pooledDecoder, size),
+	}
+	p.stats.Size = size
+	for i := 0; i < size; i++ {
+		dcfg := NewConfig()
+		if err := dcfg.setValues("NewDecoderPool", values); err != nil {
+			dcfg.Close()
+			p.Close()
+			return nil, err
+		}
+		dec, err := NewDecoder(dcfg)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		state := &pooledDecoder{
+			search:   dec.ActiveSearch(),
+			searches: make(map[string]bool),
+		}
+		for _, s := range dec.Searches() {
+			state.searches[s.Name] = true
+		}
+		p.decoders[dec] = state
+		p.free <- dec
+	}
+	p.created = time.Now()
+	return p, nil
+}
+
+// Acquire takes a decoder from the pool, waiting until one is released or ctx is done.
+// The decoder must be returned with DecoderPool.Release() when no longer needed
+// and must not be closed by the caller.
+func (p *DecoderPool) Acquire(ctx context.Context) (*Decoder, error) {
+	start := time.Now()
+	p.mux.Lock()
+	if p.closed {
+		p.mux.Unlock()
+		return nil, &Error{Op: "Acquire", Err: ErrPoolClosed}
+	}
+	p.stats.Waiting++
+	p.mux.Unlock()
+
+	var dec *Decoder
+	var err error
+	select {
+	case dec = <-p.free:
+	case <-p.done:
+		err = &Error{Op: "Acquire", Err: ErrPoolClosed}
+	case <-ctx.Done():
+		err = &Error{Op: "Acquire", Err: ctx.Err()}
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.stats.Waiting--
+	p.stats.WaitTime += time.Since(start)
+	if err != nil {
+		return nil, err
+	}
+	if p.closed {
+		// the pool has been closed while waiting
+		dec.Close()
+		return nil, &Error{Op: "Acquire", Err: ErrPoolClosed}
+	}
+	state := p.decoders[dec]
+	state.inUse = true
+	state.acquired = time.Now()
+	p.stats.InUse++
+	p.stats.Acquired++
+	return dec, nil
+}
+
+// Release resets the decoder and returns it to the pool. An utterance in progress
+// is ended, the search active at the creation of the pool is restored and
+// the searches added since then are removed.
+//
+// Release panics if the decoder does not belong to the pool or is not acquired.
+func (p *DecoderPool) Release(dec *Decoder) {
+	p.mux.Lock()
+	state, ok := p.decoders[dec]
+	if !ok || !state.inUse {
+		p.mux.Unlock()
+		panic("sphinx: release of a decoder not acquired from the pool")
+	}
+	state.inUse = false
+	p.stats.InUse--
+	p.stats.BusyTime += time.Since(state.acquired)
+	p.mux.Unlock()
+
+	state.reset(dec)
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.closed {
+		dec.Close()
+		return
+	}
+	p.free <- dec
+}
+
+// reset brings the decoder back to the state it had when the pool was created.
+func (s *pooledDecoder) reset(dec *Decoder) {
+	if dec.UttStarted() {
+		dec.EndUtt()
+	}
+	if dec.ActiveSearch() != s.search {
+		dec.SetSearch(s.search)
+	}
+	for _, search := range dec.Searches() {
+		if !s.searches[search.Name] {
+			dec.RemoveSearch(search.Name)
+		}
+	}
+	dec.StartStream()
+}
+
+// Stats gets the utilisation statistics of the pool.
+func (p *DecoderPool) Stats() PoolStats {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	stats := p.stats
+	now := time.Now()
+	for _, state := range p.decoders {
+		if state.inUse {
+			stats.BusyTime += now.Sub(state.acquired)
+		}
+	}
+	if total := now.Sub(p.created) * time.Duration(stats.Size); total > 0 {
+		stats.Utilization = float64(stats.BusyTime) / float64(total)
+	}
+	return stats
+}
+
+// Size gets the number of decoders in the pool.
+func (p *DecoderPool) Size() int {
+	return p.stats.Size
+}
+
+// Close frees the idle decoders, the ones in use are freed when released.
+// Pending and subsequent Acquire calls fail with ErrPoolClosed.
+func (p *DecoderPool) Close() error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	close(p.done)
+	for {
+		select {
+		case dec := <-p.free:
+			dec.Close()
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package sphinx
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// Utterance is the recognition result of a single utterance.
+type Utterance struct {
+	// Text is the best hypothesis string.
+	Text string
+	// Score is the path score of the best hypothesis.
+	Score int32
+	// Segments is the word segmentation of the best hypothesis.
+	Segments []Segment
+	// Start and End give the stream-relative time range of the utterance.
+	Start time.Duration
+	End   time.Duration
+}
+
+// TranscribeOptions configures Recognizer and Transcribe, the zero value is ready to use.
+type TranscribeOptions struct {
+	// ByteOrder of the 16-bit signed PCM input, defaults to binary.LittleEndian.
+	ByteOrder binary.ByteOrder
+	// ChunkSize is the number of samples to read and process at once, defaults to 2048.
+	ChunkSize int
+	// NoSearch enables only feature extraction while the speech goes on, deferring the search
+	// until the end of utterance. Useful when the audio must be consumed in real time.
+	NoSearch bool
+	// EmitEmpty enables reporting utterances with an empty hypothesis.
+	EmitEmpty bool
+	// OnSpeechStart is called when the speech is detected after silence.
+	OnSpeechStart func()
+	// SampleRate of the input in Hz, the audio is resampled when it differs from the decoder's
+	// -samprate. Defaults to the decoder's sample rate.
+	SampleRate int
+	// ResampleQuality is used when the input is resampled.
+	ResampleQuality ResampleQuality
+}
+
+const defaultChunkSize = 2048
+
+// Recognizer splits the continuous audio stream into utterances using the
+// voice activity detection of the decoder and reports recognition results for each of them.
+type Recognizer struct {
+	dec  *Decoder
+	opts TranscribeOptions
+	fn   func(u Utterance)
+
+	inSpeech bool
+	rs       *Resampler
+	buf      []int16
+}
+
+// NewRecognizer creates a new recognizer for the decoder, fn will be called with the
+// result of each utterance. Options may be nil to use the defaults.
+//
+// The decoder must not be used for anything else while the recognizer is running.
+func NewRecognizer(dec *Decoder, opts *TranscribeOptions, fn func(u Utterance)) *Recognizer {
+	r := &Recognizer{
+		dec: dec,
+		fn:  fn,
+	}
+	if opts != nil {
+		r.opts = *opts
+	}
+	if r.opts.ByteOrder == nil {
+		r.opts.ByteOrder = binary.LittleEndian
+	}
+	if r.opts.ChunkSize <= 0 {
+		r.opts.ChunkSize = defaultChunkSize
+	}
+	return r
+}
+
+// Start starts processing of a new stream and the first utterance.
+func (r *Recognizer) Start() error {
+	r.inSpeech = false
+	if rate := int(r.dec.SampleRate()); r.opts.SampleRate > 0 && r.opts.SampleRate != rate {
+		if r.rs == nil || r.rs.OutRate() != rate {
+			rs, err := NewResampler(r.opts.SampleRate, rate, r.opts.ResampleQuality)
+			if err != nil {
+				return err
+			}
+			r.rs = rs
+		}
+		r.rs.Reset()
+	} else {
+		r.rs = nil
+	}
+	if err := r.dec.StartStream(); err != nil {
+		return err
+	}
+	return r.dec.StartUtt()
+}
+
+// Process decodes a chunk of raw audio. When the speech to silence transition is
+// detected, it ends the current utterance, reports the result and starts a new utterance.
+func (r *Recognizer) Process(samples []int16) error {
+	if r.rs != nil {
+		r.buf = r.rs.Process(samples, r.buf[:0])
+		samples = r.buf
+	}
+	if _, err := r.dec.ProcessRaw(samples, r.opts.NoSearch, false); err != nil {
+		return err
+	}
+	if r.dec.IsInSpeech() {
+		if !r.inSpeech {
+			r.inSpeech = true
+			if r.opts.OnSpeechStart != nil {
+				r.opts.OnSpeechStart()
+			}
+		}
+		return nil
+	}
+	if r.inSpeech {
+		if err := r.endUtt(); err != nil {
+			return err
+		}
+		return r.dec.StartUtt()
+	}
+	return nil
+}
+
+// Flush ends the current utterance and reports its result if there was any speech.
+// Use Recognizer.Start() to start processing again.
+func (r *Recognizer) Flush() error {
+	if !r.dec.UttStarted() {
+		return nil
+	}
+	if r.rs != nil {
+		// decode the audio delayed by the resampler
+		r.buf = r.rs.Flush(r.buf[:0])
+		if _, err := r.dec.ProcessRaw(r.buf, r.opts.NoSearch, false); err != nil {
+			return err
+		}
+	}
+	if !r.inSpeech {
+		return r.dec.EndUtt()
+	}
+	return r.endUtt()
+}
+
+func (r *Recognizer) endUtt() error {
+	r.inSpeech = false
+	if err := r.dec.EndUtt(); err != nil {
+		return err
+	}
+	u := r.dec.utterance()
+	if len(u.Text) == 0 && !r.opts.EmitEmpty {
+		return nil
+	}
+	r.fn(u)
+	return nil
+}
+
+// utterance collects the result of the last utterance.
+func (d *Decoder) utterance() Utterance {
+	u := Utterance{
+		Segments: d.Segments(),
+	}
+	u.Text, u.Score = d.Hypothesis()
+	if len(u.Segments) > 0 {
+		u.Start = u.Segments[0].Start
+		u.End = u.Segments[len(u.Segments)-1].End
+	}
+	return u
+}
+
+// Run reads 16-bit signed PCM audio from rd and processes it in chunks until
+// io.EOF or an error is returned by rd, or until ctx is cancelled. The last utterance
+// is flushed when the input is exhausted.
+//
+// Note that cancellation is checked between chunks, a blocked read is not interrupted.
+func (r *Recognizer) Run(ctx context.Context, rd io.Reader) error {
+	if err := r.Start(); err != nil {
+		return err
+	}
+	buf := make([]byte, r.opts.ChunkSize*2)
+	samples := make([]int16, r.opts.ChunkSize)
+	var pending int
+	for {
+		if err := ctx.Err(); err != nil {
+			r.Flush()
+			return err
+		}
+		n, readErr := rd.Read(buf[pending:])
+		n += pending
+		count := n / 2
+		for i := 0; i < count; i++ {
+			samples[i] = int16(r.opts.ByteOrder.Uint16(buf[i*2:]))
+		}
+		// keep the odd byte for the next read
+		pending = n % 2
+		if pending > 0 {
+			buf[0] = buf[n-1]
+		}
+		if count > 0 {
+			if err := r.Process(samples[:count]); err != nil {
+				r.Flush()
+				return err
+			}
+		}
+		if readErr == io.EOF {
+			return r.Flush()
+		} else if readErr != nil {
+			r.Flush()
+			return readErr
+		}
+	}
+}
+
+// Transcribe reads 16-bit signed PCM audio from rd in a new goroutine and sends
+// the recognized utterances to the returned channel. The channel is closed when
+// the input is exhausted, ctx is cancelled or an error occurs, then the
+// error (or nil) is sent to the error channel. Options may be nil to use the defaults.
+//
+// The decoder must not be used for anything else until the channels are closed.
+func Transcribe(ctx context.Context, dec *Decoder, rd io.Reader, opts *TranscribeOptions) (<-chan Utterance, <-chan error) {
+	results := make(chan Utterance)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		r := NewRecognizer(dec, opts, func(u Utterance) {
+			select {
+			case results <- u:
+			case <-ctx.Done():
+			}
+		})
+		err := r.Run(ctx, rd)
+		close(results)
+		errs <- err
+	}()
+	return results, errs
+}
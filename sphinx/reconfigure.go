@@ -0,0 +1,305 @@
+package sphinx
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/xlab/pocketsphinx-go/pocketsphinx"
+)
+
+// dictWord is a word added to the dictionary with Decoder.AddWord().
+type dictWord struct {
+	word   string
+	phones string
+}
+
+// configSearchOptions set up the default search, in the order of precedence used by pocketsphinx.
+var configSearchOptions = []string{"-kws", "-keyphrase", "-fsg", "-jsgf", "-allphone", "-lm"}
+
+// searchOptions are only used to set up the default search, changing them
+// does not require to reload the acoustic model and the dictionary.
+var searchOptions = map[string]bool{
+	"-kws":           true,
+	"-keyphrase":     true,
+	"-kws_threshold": true,
+	"-kws_plp":       true,
+	"-kws_delay":     true,
+	"-fsg":           true,
+	"-jsgf":          true,
+	"-toprule":       true,
+	"-allphone":      true,
+	"-allphone_ci":   true,
+	"-lm":            true,
+}
+
+// acousticOptions define the acoustic model, an adaptation transform is dropped when they change.
+var acousticOptions = map[string]bool{
+	"-hmm":        true,
+	"-mdef":       true,
+	"-mean":       true,
+	"-var":        true,
+	"-tmat":       true,
+	"-mixw":       true,
+	"-sendump":    true,
+	"-senmgau":    true,
+	"-featparams": true,
+	"-feat":       true,
+	"-ceplen":     true,
+	"-lda":        true,
+	"-ldadim":     true,
+	"-mllr":       true,
+}
+
+// Reconfigure reinitializes the decoder with updated configuration.
+//
+// This function allows you to switch the acoustic model, dictionary,
+// or other configuration without creating an entirely new decoding
+// object. The decoder takes ownership of cfg and closes its previous configuration.
+// If cfg is nil, the current configuration is reloaded with the changes made to it
+// since the decoder has been initialized. If no option changed, the decoder is left as is.
+//
+// Returns the names of the changed options, e.g. "-lm". When only the options of the
+// default search changed (-lm, -jsgf, -kws and the like), the search is replaced without
+// reloading the acoustic model and the dictionary. Otherwise the decoder is reinitialized
+// and the state set up through this package is restored: the words added with Decoder.AddWord(),
+// the searches and the active one, and the transform set with Decoder.UpdateMLLR() unless
+// the acoustic model changed. The failures to restore the state are reported along with
+// the changed options.
+func (d *Decoder) Reconfigure(cfg *Config) (changed []string, err error) {
+	defer d.trackLog()()
+	if d.uttStarted {
+		return nil, &Error{Op: "Reconfigure", Err: ErrAlreadyStarted}
+	}
+	if cfg == nil {
+		cfg = d.cfg
+	}
+	values := argValues(cfg.CommandLn())
+	changed = diffValues(d.applied, values)
+	if cfg != d.cfg {
+		// the C decoder keeps its own reference to the arguments until reinitialized
+		d.cfg.Close()
+		d.cfg = cfg
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	onlySearch := hasConfigSearch(values)
+	for _, name := range changed {
+		onlySearch = onlySearch && searchOptions[name]
+	}
+	if onlySearch {
+		if err := d.replaceDefaultSearch(values); err != nil {
+			return changed, err
+		}
+		d.applied = values
+		return changed, nil
+	}
+	return changed, d.reinit(cfg, values, changed)
+}
+
+func diffValues(prev, next map[string]interface{}) []string {
+	var changed []string
+	for name, v := range next {
+		if prev[name] != v {
+			changed = append(changed, name)
+		}
+	}
+	for name := range prev {
+		if _, ok := next[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func hasConfigSearch(values map[string]interface{}) bool {
+	for _, name := range configSearchOptions {
+		if _, ok := values[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// setConfigSearch registers the search defined by the configuration values under the name.
+func (d *Decoder) setConfigSearch(name string, values map[string]interface{}) error {
+	str := func(opt string) string {
+		s, _ := values[opt].(string)
+		return s
+	}
+	switch {
+	case len(str("-kws")) > 0:
+		return d.SetKws(name, str("-kws"))
+	case len(str("-keyphrase")) > 0:
+		return d.SetKeyphrase(name, str("-keyphrase"))
+	case len(str("-fsg")) > 0:
+		lw, _ := values["-lw"].(float64)
+		fsg, err := NewFSGFromFile(String(str("-fsg")), d.LogMath(), float32(lw))
+		if err != nil {
+			return err
+		}
+		defer fsg.Close()
+		return d.SetFSG(name, fsg)
+	case len(str("-jsgf")) > 0:
+		return d.SetJSGFFile(name, str("-jsgf"))
+	case len(str("-allphone")) > 0:
+		return d.SetAllphoneFile(name, str("-allphone"))
+	case len(str("-lm")) > 0:
+		return d.SetLMFile(name, str("-lm"))
+	}
+	return nil
+}
+
+// replaceDefaultSearch replaces the search set up from the configuration, keeping the models.
+func (d *Decoder) replaceDefaultSearch(values map[string]interface{}) error {
+	// the searches read their parameters from the configuration of the decoder
+	opt := make(map[String]interface{})
+	for name := range searchOptions {
+		if v, ok := values[name]; ok {
+			opt[String(name)] = v
+		}
+	}
+	optToCommandLn(pocketsphinx.GetConfig(d.dec), opt)
+
+	if d.ActiveSearch() != defaultSearch {
+		err := d.setConfigSearch(defaultSearch, values)
+		d.dropSearch(defaultSearch)
+		return err
+	}
+	// the active search cannot be replaced, switch to a temporary one meanwhile
+	tmp := defaultSearch + ".reconfigure"
+	if err := d.setConfigSearch(tmp, values); err != nil {
+		return err
+	}
+	if err := d.SetSearch(tmp); err != nil {
+		d.RemoveSearch(tmp)
+		return err
+	}
+	err := d.setConfigSearch(defaultSearch, values)
+	d.dropSearch(defaultSearch)
+	d.SetSearch(defaultSearch)
+	d.RemoveSearch(tmp)
+	return err
+}
+
+// savedSearch keeps a search registered through this package across reinitialization.
+type savedSearch struct {
+	name string
+	kind SearchKind
+	lm   *NGramModel
+	fsg  *FSG
+	src  searchSource
+}
+
+func (s *savedSearch) close() {
+	if s.lm != nil {
+		s.lm.Close()
+	}
+	if s.fsg != nil {
+		s.fsg.Close()
+	}
+	if s.src.lm != nil {
+		s.src.lm.Close()
+	}
+}
+
+// reinit reinitializes the decoder and restores the state lost with the searches and the models.
+func (d *Decoder) reinit(cfg *Config, values map[string]interface{}, changed []string) error {
+	active := d.ActiveSearch()
+	saved := make([]savedSearch, 0, len(d.searches))
+	for name, kind := range d.searches {
+		s := savedSearch{name: name, kind: kind, src: d.sources[name]}
+		switch kind {
+		case SearchLM:
+			if lm := d.LM(name); lm != nil {
+				s.lm = lm.Retain()
+			}
+		case SearchFSG, SearchJSGF:
+			if fsg := d.FSG(name); fsg != nil {
+				s.fsg = fsg.Retain()
+			}
+		}
+		saved = append(saved, s)
+	}
+	sort.Slice(saved, func(i, j int) bool {
+		return saved[i].name < saved[j].name
+	})
+	defer func() {
+		for i := range saved {
+			saved[i].close()
+		}
+	}()
+	// the sources are moved to the saved searches
+	d.searches = make(map[string]SearchKind)
+	d.sources = make(map[string]searchSource)
+	for _, name := range changed {
+		if acousticOptions[name] && d.mllr != nil {
+			d.mllr.Close()
+			d.mllr = nil
+		}
+	}
+
+	mark := markErrors()
+	if pocketsphinx.Reinit(d.dec, cfg.CommandLn()) < 0 {
+		err := newError(mark, "Reconfigure", "", ErrFailed)
+		if verr := cfg.Validate(); verr != nil {
+			err.Err = fmt.Errorf("%w: %w", ErrFailed, verr)
+		}
+		return err
+	}
+	d.applied = values
+
+	var errs []error
+	// the words are added first to make them known to the searches being restored,
+	// the last one updates the default search
+	words := d.words
+	d.words = nil
+	for i, w := range words {
+		_, err := d.AddWord(String(w.word), String(w.phones), i == len(words)-1)
+		if err != nil && !errors.Is(err, ErrWordExists) {
+			errs = append(errs, err)
+		}
+	}
+	for _, s := range saved {
+		if d.SearchKind(s.name) != SearchUnknown {
+			// set up again from the configuration
+			continue
+		}
+		var err error
+		switch {
+		case s.lm != nil:
+			err = d.SetLM(s.name, s.lm)
+		case s.fsg != nil:
+			if err = d.SetFSG(s.name, s.fsg); err == nil {
+				d.searches[s.name] = s.kind
+			}
+		case s.kind == SearchKeyphrase:
+			err = d.SetKeyphrase(s.name, s.src.keyphrase)
+		case s.kind == SearchKws:
+			err = d.SetKws(s.name, s.src.file)
+		case s.kind == SearchAllphone && s.src.lm != nil:
+			err = d.SetAllphone(s.name, s.src.lm)
+		case s.kind == SearchAllphone:
+			err = d.SetAllphoneFile(s.name, s.src.file)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(active) > 0 && active != d.ActiveSearch() && d.SearchKind(active) != SearchUnknown {
+		if err := d.SetSearch(active); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if d.mllr != nil {
+		mllr := d.mllr
+		d.mllr = nil
+		if _, err := d.UpdateMLLR(mllr); err != nil {
+			errs = append(errs, err)
+		}
+		mllr.Close()
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,249 @@
+package sphinx
+
+import (
+	"fmt"
+	"math"
+)
+
+// ResampleQuality selects the trade-off between the quality and the cost of resampling.
+type ResampleQuality int
+
+const (
+	// ResampleDefault is the same as ResampleMedium.
+	ResampleDefault ResampleQuality = iota
+	// ResampleFast uses a short filter, suitable for low-power devices.
+	ResampleFast
+	// ResampleMedium is good enough for speech recognition.
+	ResampleMedium
+	// ResampleHigh uses a long filter with a narrow transition band.
+	ResampleHigh
+)
+
+// resampleFilter describes the lowpass filter used for each quality.
+type resampleFilter struct {
+	// taps is the number of taps per polyphase branch.
+	taps int
+	// beta is the Kaiser window parameter.
+	beta float64
+	// rolloff is the cutoff frequency relative to the lower Nyquist frequency.
+	rolloff float64
+}
+
+var resampleFilters = map[ResampleQuality]resampleFilter{
+	ResampleFast:   {taps: 16, beta: 5, rolloff: 0.85},
+	ResampleMedium: {taps: 32, beta: 8, rolloff: 0.9},
+	ResampleHigh:   {taps: 64, beta: 10, rolloff: 0.95},
+}
+
+// Resampler converts audio between two sample rates using a polyphase windowed sinc filter.
+// It keeps the filter state between calls, so a stream can be processed in chunks of any size,
+// the output is delayed by Latency() input samples. A Resampler is not safe for concurrent use.
+type Resampler struct {
+	inRate  int
+	outRate int
+	// up and down are the interpolation and decimation factors.
+	up   int
+	down int
+	taps int
+	// delay of the filter in upsampled samples.
+	delay int
+	// phases holds up filters of taps coefficients each, in reversed order.
+	phases []float32
+
+	// hist holds the input samples still needed by the filter.
+	hist []float32
+	// pos is the position of the filter window for the next output sample, in upsampled hist.
+	pos int
+	// inCount and outCount are the numbers of samples consumed and produced since Reset.
+	inCount  int64
+	outCount int64
+	// in and out are scratch buffers for int16 conversion.
+	in  []float32
+	out []float32
+}
+
+// NewResampler creates a resampler converting audio from inRate to outRate Hz.
+func NewResampler(inRate, outRate int, quality ResampleQuality) (*Resampler, error) {
+	if inRate <= 0 || outRate <= 0 {
+		return nil, &Error{
+			Op:  "NewResampler",
+			Arg: fmt.Sprintf("%d to %d Hz", inRate, outRate),
+			Err: ErrBadSampleRate,
+		}
+	}
+	if quality == ResampleDefault {
+		quality = ResampleMedium
+	}
+	spec, ok := resampleFilters[quality]
+	if !ok {
+		return nil, &Error{
+			Op:  "NewResampler",
+			Arg: fmt.Sprintf("quality %d", quality),
+			Err: ErrUnsupportedFormat,
+		}
+	}
+	g := gcd(inRate, outRate)
+	r := &Resampler{
+		inRate:  inRate,
+		outRate: outRate,
+		up:      outRate / g,
+		down:    inRate / g,
+	}
+	if r.up == r.down {
+		// no conversion needed, a single unit tap passes the input through
+		r.taps = 1
+		r.phases = []float32{1}
+	} else {
+		r.taps, r.delay, r.phases = designPolyphase(r.up, r.down, spec)
+	}
+	r.Reset()
+	return r, nil
+}
+
+// designPolyphase designs the Kaiser windowed sinc lowpass filter at the upsampled rate
+// and splits it into up branches. It returns the number of taps per branch and the delay
+// of the filter in upsampled samples.
+func designPolyphase(up, down int, spec resampleFilter) (taps, delay int, phases []float32) {
+	// the filter length is proportional to the lower rate to keep the transition band
+	// width independent of the conversion ratio
+	factor := up
+	if down > up {
+		factor = down
+	}
+	taps = (spec.taps*factor + up - 1) / up
+	n := up * taps
+	if n%2 == 0 {
+		// odd length gives an integer delay, the last coefficient stays zero
+		n--
+	}
+	delay = (n - 1) / 2
+	// cutoff in cycles per upsampled sample
+	fc := 0.5 * spec.rolloff / float64(factor)
+	i0beta := besselI0(spec.beta)
+	phases = make([]float32, up*taps)
+	for i := 0; i < n; i++ {
+		x := float64(i - delay)
+		h := 2 * fc
+		if x != 0 {
+			h = math.Sin(2*math.Pi*fc*x) / (math.Pi * x)
+		}
+		ratio := x / float64(delay)
+		w := besselI0(spec.beta*math.Sqrt(1-ratio*ratio)) / i0beta
+		// coefficient i belongs to branch i%up as its tap i/up, taps are stored reversed
+		// so the branch can be applied to the history in the natural order
+		p, k := i%up, i/up
+		phases[p*taps+taps-1-k] = float32(h * w * float64(up))
+	}
+	return taps, delay, phases
+}
+
+// besselI0 computes the zeroth order modified Bessel function of the first kind.
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; k < 50; k++ {
+		term *= (x / 2) / float64(k)
+		sum += term * term
+		if term*term < sum*1e-12 {
+			break
+		}
+	}
+	return sum
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// InRate returns the input sample rate.
+func (r *Resampler) InRate() int {
+	return r.inRate
+}
+
+// OutRate returns the output sample rate.
+func (r *Resampler) OutRate() int {
+	return r.outRate
+}
+
+// Latency returns the delay introduced by the filter, in input samples.
+func (r *Resampler) Latency() int {
+	return (r.delay + r.up - 1) / r.up
+}
+
+// Reset clears the filter state to start a new stream.
+func (r *Resampler) Reset() {
+	r.hist = append(r.hist[:0], make([]float32, r.taps-1)...)
+	// start at the filter delay so that the output is aligned with the input
+	r.pos = r.delay
+	r.inCount = 0
+	r.outCount = 0
+}
+
+// ProcessFloat resamples in and appends the result to out, returning the extended slice.
+func (r *Resampler) ProcessFloat(in, out []float32) []float32 {
+	r.hist = append(r.hist, in...)
+	r.inCount += int64(len(in))
+	taps := r.taps
+	for {
+		i := r.pos / r.up
+		if i+taps > len(r.hist) {
+			break
+		}
+		p := r.pos % r.up
+		branch := r.phases[p*taps : (p+1)*taps]
+		window := r.hist[i : i+taps]
+		var acc float32
+		for k, c := range branch {
+			acc += c * window[k]
+		}
+		out = append(out, acc)
+		r.outCount++
+		r.pos += r.down
+	}
+	// drop the samples the filter will not need anymore
+	if drop := r.pos / r.up; drop > 0 {
+		r.hist = append(r.hist[:0], r.hist[drop:]...)
+		r.pos -= drop * r.up
+	}
+	return out
+}
+
+// Process resamples in and appends the result to out, returning the extended slice.
+func (r *Resampler) Process(in, out []int16) []int16 {
+	r.in = r.in[:0]
+	for _, v := range in {
+		r.in = append(r.in, float32(v))
+	}
+	r.out = r.ProcessFloat(r.in, r.out[:0])
+	for _, v := range r.out {
+		out = append(out, clampInt16(float64(v)))
+	}
+	return out
+}
+
+// FlushFloat appends the output delayed by the filter to out and resets the resampler.
+func (r *Resampler) FlushFloat(out []float32) []float32 {
+	// the output ends where the input ends
+	total := (r.inCount*int64(r.up) + int64(r.down) - 1) / int64(r.down)
+	remaining := int(total - r.outCount)
+	if remaining > 0 {
+		n := len(out)
+		out = r.ProcessFloat(make([]float32, r.Latency()+1), out)
+		if len(out) > n+remaining {
+			out = out[:n+remaining]
+		}
+	}
+	r.Reset()
+	return out
+}
+
+// Flush appends the output delayed by the filter to out and resets the resampler.
+func (r *Resampler) Flush(out []int16) []int16 {
+	r.out = r.FlushFloat(r.out[:0])
+	for _, v := range r.out {
+		out = append(out, clampInt16(float64(v)))
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package sphinx
+
+import (
+	"math"
+	"testing"
+)
+
+func sine(rate int, freq float64, n int, amp float64) []float32 {
+	out := make([]float32, n)
+	for i := range out {
+		out[i] = float32(amp * math.Sin(2*math.Pi*freq*float64(i)/float64(rate)))
+	}
+	return out
+}
+
+// toneLevel fits a sine of the given frequency to x and returns its amplitude
+// and the RMS of the residual.
+func toneLevel(x []float32, rate int, freq float64) (amp, residual float64) {
+	var s, c float64
+	for i, v := range x {
+		w := 2 * math.Pi * freq * float64(i) / float64(rate)
+		s += float64(v) * math.Sin(w)
+		c += float64(v) * math.Cos(w)
+	}
+	s *= 2 / float64(len(x))
+	c *= 2 / float64(len(x))
+	var sum float64
+	for i, v := range x {
+		w := 2 * math.Pi * freq * float64(i) / float64(rate)
+		d := float64(v) - s*math.Sin(w) - c*math.Cos(w)
+		sum += d * d
+	}
+	return math.Hypot(s, c), math.Sqrt(sum / float64(len(x)))
+}
+
+func TestResampleSine(t *testing.T) {
+	cases := []struct {
+		in, out int
+		quality ResampleQuality
+		minSNR  float64
+	}{
+		{8000, 16000, ResampleFast, 40},
+		{8000, 16000, ResampleMedium, 60},
+		{44100, 16000, ResampleMedium, 60},
+		{48000, 16000, ResampleHigh, 70},
+		{22050, 16000, ResampleDefault, 60},
+		{16000, 16000, ResampleDefault, 100},
+	}
+	const freq = 1000
+	for _, tc := range cases {
+		r, err := NewResampler(tc.in, tc.out, tc.quality)
+		if err != nil {
+			t.Fatal(err)
+		}
+		in := sine(tc.in, freq, tc.in, 0.5)
+		out := r.ProcessFloat(in, nil)
+		out = r.FlushFloat(out)
+		if len(out) != tc.out {
+			t.Errorf("%d -> %d: got %d samples, expected %d", tc.in, tc.out, len(out), tc.out)
+			continue
+		}
+		// skip the edges where the filter sees the zero padding
+		edge := tc.out / 10
+		amp, res := toneLevel(out[edge:len(out)-edge], tc.out, freq)
+		// the output must be in phase with the input
+		ref := sine(tc.out, freq, tc.out, 0.5)
+		var phaseErr float64
+		for i := edge; i < len(out)-edge; i++ {
+			phaseErr = math.Max(phaseErr, math.Abs(float64(out[i]-ref[i])))
+		}
+		snr := 20 * math.Log10(amp/math.Max(res, 1e-12))
+		if math.Abs(amp-0.5) > 0.005 || snr < tc.minSNR || phaseErr > 0.01 {
+			t.Errorf("%d -> %d quality %d: amplitude %.4f, SNR %.1f dB, max error %.4f",
+				tc.in, tc.out, tc.quality, amp, snr, phaseErr)
+		}
+	}
+}
+
+func TestResampleStopband(t *testing.T) {
+	// 6 kHz is above the Nyquist frequency of 8 kHz audio and must be filtered out
+	r, err := NewResampler(48000, 8000, ResampleMedium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := r.FlushFloat(r.ProcessFloat(sine(48000, 6000, 48000, 0.5), nil))
+	var sum float64
+	for _, v := range out[800 : len(out)-800] {
+		sum += float64(v) * float64(v)
+	}
+	rms := math.Sqrt(sum / float64(len(out)-1600))
+	if att := 20 * math.Log10(rms/(0.5/math.Sqrt2)); att > -60 {
+		t.Errorf("stopband attenuation %.1f dB", att)
+	}
+}
+
+func TestResampleStreaming(t *testing.T) {
+	in := make([]int16, 44100)
+	for i, v := range sine(44100, 440, len(in), 20000) {
+		in[i] = int16(v)
+	}
+	whole, err := NewResampler(44100, 16000, ResampleMedium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := whole.Flush(whole.Process(in, nil))
+
+	chunked, err := NewResampler(44100, 16000, ResampleMedium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []int16
+	for pos, size := 0, 1; pos < len(in); size = size*3%1021 + 1 {
+		end := pos + size
+		if end > len(in) {
+			end = len(in)
+		}
+		out = chunked.Process(in[pos:end], out)
+		pos = end
+	}
+	out = chunked.Flush(out)
+	if len(out) != len(expected) {
+		t.Fatalf("got %d samples in chunks, expected %d", len(out), len(expected))
+	}
+	for i := range out {
+		if out[i] != expected[i] {
+			t.Fatalf("sample %d: got %d in chunks, expected %d", i, out[i], expected[i])
+		}
+	}
+}
+
+func TestResampleBadRate(t *testing.T) {
+	if _, err := NewResampler(0, 16000, ResampleDefault); err == nil {
+		t.Error("expected an error for zero input rate")
+	}
+}
@@ -0,0 +1,362 @@
+package sphinx
+
+import (
+	"sort"
+
+	"github.com/xlab/pocketsphinx-go/pocketsphinx"
+)
+
+// SearchKind is a kind of search module registered in the decoder.
+type SearchKind int
+
+// Kinds of search modules.
+const (
+	// SearchUnknown is used when the kind of search could not be determined.
+	SearchUnknown SearchKind = iota
+	// SearchLM is an N-Gram language model search.
+	SearchLM
+	// SearchFSG is a finite state grammar search.
+	SearchFSG
+	// SearchJSGF is a finite state grammar search built from a JSGF grammar.
+	SearchJSGF
+	// SearchKws is a keyword spotting search with keyphrases loaded from a file.
+	SearchKws
+	// SearchKeyphrase is a keyword spotting search for a single keyphrase.
+	SearchKeyphrase
+	// SearchAllphone is a phoneme recognition search with a phonetic language model.
+	SearchAllphone
+)
+
+func (k SearchKind) String() string {
+	switch k {
+	case SearchLM:
+		return "lm"
+	case SearchFSG:
+		return "fsg"
+	case SearchJSGF:
+		return "jsgf"
+	case SearchKws:
+		return "kws"
+	case SearchKeyphrase:
+		return "keyphrase"
+	case SearchAllphone:
+		return "allphone"
+	default:
+		return "unknown"
+	}
+}
+
+// defaultSearch is the name of the search set up from the configuration.
+const defaultSearch = "_default"
+
+// Search describes a named search module registered in the decoder.
+type Search struct {
+	Name string
+	Kind SearchKind
+}
+
+// SetSearch activates the named search. The search must be registered
+// beforehand, e.g. with Decoder.SetKeyphrase() or Decoder.SetKws().
+func (d *Decoder) SetSearch(name string) error {
+	defer d.trackLog()()
+	if d.SearchKind(name) == SearchUnknown {
+		return &Error{Op: "SetSearch", Arg: name, Err: ErrUnknownSearch}
+	}
+	mark := markErrors()
+	ret := pocketsphinx.SetSearch(d.dec, String(name).S())
+	if ret < 0 {
+		return newError(mark, "SetSearch", name, ErrFailed)
+	}
+	return nil
+}
+
+// ActiveSearch gets the name of the current search. Returns empty string
+// if there is no active search.
+func (d *Decoder) ActiveSearch() string {
+	return pocketsphinx.RawString(pocketsphinx.GetSearch(d.dec)).Copy()
+}
+
+// RemoveSearch unsets the named search and releases resources associated with it.
+// The active search cannot be removed.
+func (d *Decoder) RemoveSearch(name string) error {
+	if d.SearchKind(name) == SearchUnknown {
+		return &Error{Op: "RemoveSearch", Arg: name, Err: ErrUnknownSearch}
+	}
+	mark := markErrors()
+	ret := pocketsphinx.UnsetSearch(d.dec, String(name).S())
+	if ret < 0 {
+		return newError(mark, "RemoveSearch", name, ErrFailed)
+	}
+	d.dropSearch(name)
+	return nil
+}
+
+// Searches lists all searches registered in the decoder, sorted by name.
+func (d *Decoder) Searches() []Search {
+	var searches []Search
+	for itor := pocketsphinx.GetSearchIter(d.dec); itor != nil; itor = pocketsphinx.SearchIterNext(itor) {
+		name := pocketsphinx.SearchIterVal(itor)
+		searches = append(searches, Search{
+			Name: name,
+			Kind: d.SearchKind(name),
+		})
+	}
+	sort.Slice(searches, func(i, j int) bool {
+		return searches[i].Name < searches[j].Name
+	})
+	return searches
+}
+
+// SearchKind gets the kind of the named search. Returns SearchUnknown if there
+// is no such search.
+//
+// Searches registered through this package report the kind they were registered with,
+// otherwise (e.g. for the default search set up from the configuration) the kind is
+// inferred from the search module, so JSGF searches will be reported as SearchFSG
+// and keyphrase searches as SearchKws.
+func (d *Decoder) SearchKind(name string) SearchKind {
+	if kind, ok := d.searches[name]; ok {
+		return kind
+	}
+	cname := String(name).S()
+	switch {
+	case pocketsphinx.GetLm(d.dec, cname) != nil:
+		return SearchLM
+	case pocketsphinx.GetFsg(d.dec, cname) != nil:
+		return SearchFSG
+	case len(pocketsphinx.GetKws(d.dec, cname)) > 0:
+		return SearchKws
+	}
+	for itor := pocketsphinx.GetSearchIter(d.dec); itor != nil; itor = pocketsphinx.SearchIterNext(itor) {
+		if pocketsphinx.SearchIterVal(itor) == name {
+			pocketsphinx.SearchIterFree(itor)
+			// the only kind of search not exposing its model.
+			return SearchAllphone
+		}
+	}
+	return SearchUnknown
+}
+
+// searchSource keeps what is needed to register a search again after the decoder is reinitialized,
+// for the kinds of searches whose models cannot be retrieved from the decoder.
+type searchSource struct {
+	file      string
+	keyphrase string
+	lm        *NGramModel
+}
+
+// registerSearch records the kind of the search registered through this package.
+func (d *Decoder) registerSearch(name string, kind SearchKind, src searchSource) {
+	d.dropSearch(name)
+	d.searches[name] = kind
+	if src != (searchSource{}) {
+		d.sources[name] = src
+	}
+}
+
+func (d *Decoder) dropSearch(name string) {
+	if src, ok := d.sources[name]; ok {
+		if src.lm != nil {
+			src.lm.Close()
+		}
+		delete(d.sources, name)
+	}
+	delete(d.searches, name)
+}
+
+// SetKeyphrase associates keyword search with the provided name. Activate
+// with Decoder.SetSearch()
+func (d *Decoder) SetKeyphrase(name string, keyphrase string) error {
+	defer d.trackLog()()
+	mark := markErrors()
+	ret := pocketsphinx.SetKeyphrase(d.dec, String(name).S(), String(keyphrase).S())
+	if ret < 0 {
+		return newError(mark, "SetKeyphrase", name, ErrFailed)
+	}
+	d.registerSearch(name, SearchKeyphrase, searchSource{keyphrase: keyphrase})
+	return nil
+}
+
+// SetKws associates keyword search with the provided file. Activate
+// with Decoder.SetSearch()
+func (d *Decoder) SetKws(name string, keyfile string) error {
+	defer d.trackLog()()
+	if err := checkFile("SetKws", String(keyfile)); err != nil {
+		return err
+	}
+	mark := markErrors()
+	ret := pocketsphinx.SetKws(d.dec, String(name).S(), String(keyfile).S())
+	if ret < 0 {
+		return newError(mark, "SetKws", name, ErrFailed)
+	}
+	d.registerSearch(name, SearchKws, searchSource{file: keyfile})
+	return nil
+}
+
+// SetLM associates N-Gram language model search with the provided name. Activate
+// with Decoder.SetSearch()
+//
+// The decoder retains its own reference to lm, so it is safe to close it afterwards.
+func (d *Decoder) SetLM(name string, lm *NGramModel) error {
+	defer d.trackLog()()
+	mark := markErrors()
+	ret := pocketsphinx.SetLm(d.dec, String(name).S(), lm.n)
+	if ret < 0 {
+		return newError(mark, "SetLM", name, ErrFailed)
+	}
+	d.registerSearch(name, SearchLM, searchSource{})
+	return nil
+}
+
+// SetLMFile associates N-Gram language model search with the provided name,
+// reading the model from a file. Activate with Decoder.SetSearch()
+func (d *Decoder) SetLMFile(name string, lmfile string) error {
+	defer d.trackLog()()
+	if err := checkFile("SetLMFile", String(lmfile)); err != nil {
+		return err
+	}
+	mark := markErrors()
+	ret := pocketsphinx.SetLmFile(d.dec, String(name).S(), String(lmfile).S())
+	if ret < 0 {
+		return newError(mark, "SetLMFile", name, ErrFailed)
+	}
+	d.registerSearch(name, SearchLM, searchSource{})
+	return nil
+}
+
+// LM gets the language model of the named N-Gram search. Returns nil if there is
+// no such search or it is not an N-Gram search.
+//
+// The decoder retains ownership of this object, closing it is a no-op.
+// Use NGramModel.Retain() if you wish to reuse it elsewhere.
+// Changes to the model are applied to the live search.
+func (d *Decoder) LM(name string) *NGramModel {
+	lm := pocketsphinx.GetLm(d.dec, String(name).S())
+	if lm == nil {
+		return nil
+	}
+	return &NGramModel{
+		n: lm,
+	}
+}
+
+// SetFSG associates finite state grammar search with the provided name. Activate
+// with Decoder.SetSearch()
+//
+// The decoder retains its own reference to fsg, so it is safe to close it afterwards.
+func (d *Decoder) SetFSG(name string, fsg *FSG) error {
+	defer d.trackLog()()
+	mark := markErrors()
+	ret := pocketsphinx.SetFsg(d.dec, String(name).S(), fsg.f)
+	if ret < 0 {
+		return newError(mark, "SetFSG", name, ErrFailed)
+	}
+	d.registerSearch(name, SearchFSG, searchSource{})
+	return nil
+}
+
+// FSG gets the finite state grammar of the named FSG or JSGF search. Returns nil
+// if there is no such search or it is not a grammar search.
+//
+// The decoder retains ownership of this object, closing it is a no-op.
+// Use FSG.Retain() if you wish to reuse it elsewhere.
+func (d *Decoder) FSG(name string) *FSG {
+	fsg := pocketsphinx.GetFsg(d.dec, String(name).S())
+	if fsg == nil {
+		return nil
+	}
+	return &FSG{
+		f: fsg,
+	}
+}
+
+// SetJSGF associates JSGF grammar search with the provided name. Activate
+// with Decoder.SetSearch()
+//
+// The grammar is compiled starting from the rule set by the -toprule option,
+// or from the first public rule of the grammar.
+func (d *Decoder) SetJSGF(name string, grammar *JSGF) error {
+	defer d.trackLog()()
+	mark := markErrors()
+	var rule *pocketsphinx.JSGFRule
+	if toprule := pocketsphinx.CommandLnStrR(pocketsphinx.GetConfig(d.dec), String("-toprule").S()); len(toprule) > 0 {
+		rule = pocketsphinx.JSGFGetRule(grammar.j, String(toprule).S())
+	} else {
+		rule = pocketsphinx.JSGFGetPublicRule(grammar.j)
+	}
+	if rule == nil {
+		return newError(mark, "SetJSGF", name, ErrNoRule)
+	}
+	lw := pocketsphinx.CommandLnFloatR(pocketsphinx.GetConfig(d.dec), String("-lw").S())
+	fsg := pocketsphinx.JSGFBuildFsg(grammar.j, rule, pocketsphinx.GetLogmath(d.dec), float32(lw))
+	if fsg == nil {
+		return newError(mark, "SetJSGF", name, ErrFailed)
+	}
+	ret := pocketsphinx.SetFsg(d.dec, String(name).S(), fsg)
+	pocketsphinx.FsgModelFree(fsg)
+	if ret < 0 {
+		return newError(mark, "SetJSGF", name, ErrFailed)
+	}
+	d.registerSearch(name, SearchJSGF, searchSource{})
+	return nil
+}
+
+// SetJSGFFile associates JSGF grammar search with the provided name,
+// reading the grammar from a file. Activate with Decoder.SetSearch()
+func (d *Decoder) SetJSGFFile(name string, jsgfFile string) error {
+	defer d.trackLog()()
+	if err := checkFile("SetJSGFFile", String(jsgfFile)); err != nil {
+		return err
+	}
+	mark := markErrors()
+	ret := pocketsphinx.SetJSGFFile(d.dec, String(name).S(), String(jsgfFile).S())
+	if ret < 0 {
+		return newError(mark, "SetJSGFFile", name, ErrFailed)
+	}
+	d.registerSearch(name, SearchJSGF, searchSource{})
+	return nil
+}
+
+// SetJSGFString associates JSGF grammar search with the provided name,
+// parsing the grammar from a string. Activate with Decoder.SetSearch()
+func (d *Decoder) SetJSGFString(name string, jsgf string) error {
+	defer d.trackLog()()
+	mark := markErrors()
+	ret := pocketsphinx.SetJSGFString(d.dec, String(name).S(), String(jsgf).S())
+	if ret < 0 {
+		return newError(mark, "SetJSGFString", name, ErrFailed)
+	}
+	d.registerSearch(name, SearchJSGF, searchSource{})
+	return nil
+}
+
+// SetAllphone associates phoneme recognition search with the provided name,
+// using lm as a phonetic language model. Activate with Decoder.SetSearch()
+//
+// The decoder retains its own reference to lm, so it is safe to close it afterwards.
+func (d *Decoder) SetAllphone(name string, lm *NGramModel) error {
+	defer d.trackLog()()
+	mark := markErrors()
+	ret := pocketsphinx.SetAllphone(d.dec, String(name).S(), lm.n)
+	if ret < 0 {
+		return newError(mark, "SetAllphone", name, ErrFailed)
+	}
+	d.registerSearch(name, SearchAllphone, searchSource{lm: lm.Retain()})
+	return nil
+}
+
+// SetAllphoneFile associates phoneme recognition search with the provided name,
+// reading the phonetic language model from a file. Activate with Decoder.SetSearch()
+func (d *Decoder) SetAllphoneFile(name string, lmfile string) error {
+	defer d.trackLog()()
+	if err := checkFile("SetAllphoneFile", String(lmfile)); err != nil {
+		return err
+	}
+	mark := markErrors()
+	ret := pocketsphinx.SetAllphoneFile(d.dec, String(name).S(), String(lmfile).S())
+	if ret < 0 {
+		return newError(mark, "SetAllphoneFile", name, ErrFailed)
+	}
+	d.registerSearch(name, SearchAllphone, searchSource{file: lmfile})
+	return nil
+}
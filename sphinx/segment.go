@@ -0,0 +1,81 @@
+package sphinx
+
+import (
+	"time"
+
+	"github.com/xlab/pocketsphinx-go/pocketsphinx"
+)
+
+// Segment represents a single word (or filler) in a word segmentation of the utterance.
+type Segment struct {
+	// Word is the word string of this segment, possibly a pronunciation variant, e.g. "read(2)".
+	Word string
+	// StartFrame is the first frame index in the segment.
+	StartFrame int32
+	// EndFrame is the last frame index in the segment. Frame numbers are inclusive,
+	// i.e. the actual duration is EndFrame - StartFrame + 1 frames.
+	EndFrame int32
+	// Start is the stream-relative time when the segment starts.
+	Start time.Duration
+	// End is the stream-relative time when the segment ends.
+	End time.Duration
+	// AcousticScore is the acoustic model score for this segment.
+	AcousticScore int32
+	// LMScore is the language model score for this segment.
+	LMScore int32
+	// LMBackoff is the language model backoff mode for this segment (i.e. the number
+	// of words used in calculating LMScore). This field is only meaningful for N-Gram models.
+	LMBackoff int32
+	// Posterior is the log posterior probability of this segment. Log is expressed in
+	// the log-base used in the decoder. To convert to linear floating-point, use
+	// Decoder.LogMath().Exp(prob).
+	//
+	// Unless the BestpathOption option is enabled, this will always be zero
+	// (corresponding to a posterior probability of 1.0). Even if BestpathOption is enabled,
+	// it will also be zero for a partial result.
+	Posterior int32
+}
+
+// Duration returns the duration of the segment.
+func (s Segment) Duration() time.Duration {
+	return s.End - s.Start
+}
+
+// Segments gets the word segmentation of the best hypothesis at this point in decoding.
+// Returns nil if no hypothesis is available.
+//
+// Times are derived from the frame rate of the decoder configuration (-frate)
+// and are stream-wide if decoding was started with Decoder.StartStream().
+func (d *Decoder) Segments() []Segment {
+	seg := pocketsphinx.SegIter(d.dec)
+	return collectSegments(seg, d.frameRate())
+}
+
+// frameRate gets the number of frames per second the decoder was configured with.
+func (d *Decoder) frameRate() int32 {
+	frate := int32(pocketsphinx.CommandLnIntR(pocketsphinx.GetConfig(d.dec), String("-frate").S()))
+	if frate <= 0 {
+		return 100
+	}
+	return frate
+}
+
+// collectSegments walks over the segmentation iterator until the end, which also
+// frees the iterator, copying segments data to Go memory.
+func collectSegments(seg *pocketsphinx.Seg, frate int32) []Segment {
+	var segments []Segment
+	for ; seg != nil; seg = pocketsphinx.SegNext(seg) {
+		var s Segment
+		s.Word = pocketsphinx.RawString(pocketsphinx.SegWord(seg)).Copy()
+		pocketsphinx.SegFrames(seg, &s.StartFrame, &s.EndFrame)
+		s.Posterior = pocketsphinx.SegProb(seg, &s.AcousticScore, &s.LMScore, &s.LMBackoff)
+		s.Start = framesToDuration(s.StartFrame, frate)
+		s.End = framesToDuration(s.EndFrame+1, frate)
+		segments = append(segments, s)
+	}
+	return segments
+}
+
+func framesToDuration(frames, frate int32) time.Duration {
+	return time.Duration(frames) * time.Second / time.Duration(frate)
+}
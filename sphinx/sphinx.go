@@ -1,71 +1,111 @@
 package sphinx
 
 import (
-	"errors"
+	"fmt"
+	"runtime"
 	"time"
 
 	"github.com/xlab/pocketsphinx-go/pocketsphinx"
 )
 
+// Decoder is the speech recognizer, it is not safe for concurrent use.
 type Decoder struct {
+	id  uint64
 	cfg *Config
 	dec *pocketsphinx.Decoder
 
 	maxRawdataSize int32
 	rawdataBuf     [][]int16
+	uttStarted     bool
+
+	searches map[string]SearchKind
+	sources  map[string]searchSource
+	// applied holds the option values the decoder has been initialized with.
+	applied map[string]interface{}
+	// words and mllr are reapplied when the decoder is reconfigured.
+	words []dictWord
+	mllr  *MLLR
+	// g2p pronounces the words added by EnsureWords.
+	g2p G2P
 }
 
-// Config gets the configuration object for this decoder.
+// Config gets the configuration object for this decoder. The decoder owns
+// the configuration, it is closed along with the decoder.
 func (d *Decoder) Config() *Config {
 	return d.cfg
 }
 
-// NewDecoder initializes the decoder from a configuration object.
+// NewDecoder initializes the decoder from a configuration object. The decoder takes
+// ownership of cfg, which is closed along with the decoder or if the initialization fails.
+// Closing cfg before the decoder is safe, the decoder keeps its own reference to the arguments.
 func NewDecoder(cfg *Config) (*Decoder, error) {
 	if cfg == nil {
 		cfg = NewConfig()
 	}
 	dec := &Decoder{
+		id:  nextDecoderID(),
 		cfg: cfg,
-		dec: pocketsphinx.Init(cfg.CommandLn()),
+
+		searches: make(map[string]SearchKind),
+		sources:  make(map[string]searchSource),
 	}
+	defer dec.trackLog()()
+	mark := markErrors()
+	dec.dec = pocketsphinx.Init(cfg.CommandLn())
 	if dec.dec == nil {
-		cfg.Destroy()
-		err := errors.New("pocketsphinx.Init failed")
+		err := newError(mark, "NewDecoder", "", ErrFailed)
+		if verr := cfg.Validate(); verr != nil {
+			err.Err = fmt.Errorf("%w: %w", ErrFailed, verr)
+		}
+		cfg.Close()
 		return nil, err
 	}
+	dec.applied = argValues(cfg.CommandLn())
+	runtime.SetFinalizer(dec, func(d *Decoder) {
+		reportLeak("Decoder")
+		d.Close()
+	})
 	dec.SetRawDataSize(0)
 	return dec, nil
 }
 
-// Reconfigure reinitializes the decoder with updated configuration.
-//
-// This function allows you to switch the acoustic model, dictionary,
-// or other configuration without creating an entirely new decoding
-// object.
-//
-// An optional new configuration to use. If cfg is
-// nil, the previous configuration will be reloaded,
-// with any changes applied.
-func (d *Decoder) Reconfigure(cfg *Config) {
-	pocketsphinx.Reinit(d.dec, cfg.CommandLn())
+// ID gets the unique identifier of this decoder, used to attribute log
+// messages to it, see SetLogFunc.
+func (d *Decoder) ID() uint64 {
+	return d.id
+}
+
+// Close frees the decoder and its configuration.
+func (d *Decoder) Close() error {
+	d.Destroy()
+	return nil
 }
 
+// Destroy frees the decoder and its configuration, returns true if the decoder has been freed.
+//
+// Deprecated: use Close.
 func (d *Decoder) Destroy() bool {
-	if d.dec != nil {
-		ret := pocketsphinx.Free(d.dec)
-		d.dec = nil
-		return ret == 0
+	if d.dec == nil {
+		return false
+	}
+	runtime.SetFinalizer(d, nil)
+	ret := pocketsphinx.Free(d.dec)
+	d.dec = nil
+	d.cfg.Close()
+	for name := range d.sources {
+		d.dropSearch(name)
+	}
+	if d.mllr != nil {
+		d.mllr.Close()
+		d.mllr = nil
 	}
-	d.cfg.Destroy()
-	return true
+	return ret == 0
 }
 
 // LogMath gets the log-math computation object for this decoder.
 //
-// The decoder retains ownership of this pointer, so you should not attempt to
-// free it manually. Use LogMath.Retain() if you wish to
-// reuse it elsewhere.
+// The decoder retains ownership of this object, closing it is a no-op.
+// Use LogMath.Retain() if you wish to reuse it elsewhere.
 func (d *Decoder) LogMath() *LogMath {
 	return &LogMath{
 		m: pocketsphinx.GetLogmath(d.dec),
@@ -80,25 +120,36 @@ func (d *Decoder) Decoder() *pocketsphinx.Decoder {
 // UpdateMLLR adapts current acoustic model using a linear transform (Maximum Likelihood Linear Regression).
 //
 // mllr is the new transform to use, or nil to update the existing
-// transform. The decoder retains ownership of this pointer,
-// so you should not attempt to free it manually. Use
-// MLLR.Retain() if you wish to reuse it elsewhere.
+// transform. The decoder takes its own reference to mllr, so it is safe to close it afterwards.
 //
-// Returns the updated transform object for this decoder, or
-// nil on failure.
-func (d *Decoder) UpdateMLLR(mllr *MLLR) *MLLR {
+// Returns the updated transform object for this decoder. The decoder retains ownership of
+// this object, closing it is a no-op. Use MLLR.Retain() if you wish to reuse it elsewhere.
+func (d *Decoder) UpdateMLLR(mllr *MLLR) (*MLLR, error) {
+	defer d.trackLog()()
+	mark := markErrors()
 	var m *pocketsphinx.Mllr
 	if mllr == nil {
 		m = pocketsphinx.UpdateMllr(d.dec, nil)
 	} else {
-		m = pocketsphinx.UpdateMllr(d.dec, mllr.m)
+		m = pocketsphinx.UpdateMllr(d.dec, pocketsphinx.MllrRetain(mllr.m))
 	}
-	if m != nil {
-		return &MLLR{
-			m: m,
+	if m == nil {
+		if mllr != nil {
+			pocketsphinx.MllrFree(mllr.m)
 		}
+		err := newError(mark, "UpdateMLLR", "", ErrFailed)
+		return nil, err
 	}
-	return nil
+	if mllr != nil {
+		prev := d.mllr
+		d.mllr = mllr.Retain()
+		if prev != nil {
+			prev.Close()
+		}
+	}
+	return &MLLR{
+		m: m,
+	}, nil
 }
 
 // ReadDict reloads the pronunciation dictionary from a file.
@@ -111,15 +162,34 @@ func (d *Decoder) UpdateMLLR(mllr *MLLR) *MLLR {
 // dictFile is the path to dictionary file to load.
 // fillerDictFile is the path to filler dictionary to load,
 // or empty string to keep the existing filler dictionary.
-func (d *Decoder) ReadDict(dictFile, fillerDictFile String) bool {
+func (d *Decoder) ReadDict(dictFile, fillerDictFile String) error {
+	defer d.trackLog()()
+	if err := checkFile("ReadDict", dictFile); err != nil {
+		return err
+	}
+	if len(fillerDictFile) > 0 {
+		if err := checkFile("ReadDict", fillerDictFile); err != nil {
+			return err
+		}
+	}
+	mark := markErrors()
 	ret := pocketsphinx.LoadDict(d.dec, dictFile.S(), fillerDictFile.S(), end)
-	return ret == 0
+	if ret < 0 {
+		return newError(mark, "ReadDict", string(dictFile), ErrFailed)
+	}
+	// the words added before are gone with the old dictionary
+	d.words = nil
+	return nil
 }
 
 // WriteDict writes the current pronunciation dictionary to a file.
-func (d *Decoder) WriteDict(dictFile String) bool {
+func (d *Decoder) WriteDict(dictFile String) error {
+	mark := markErrors()
 	ret := pocketsphinx.SaveDict(d.dec, dictFile.S(), end)
-	return ret == 0
+	if ret < 0 {
+		return newError(mark, "WriteDict", string(dictFile), ErrFailed)
+	}
+	return nil
 }
 
 // AddWord adds a word to the pronunciation dictionary.
@@ -135,13 +205,21 @@ func (d *Decoder) WriteDict(dictFile String) bool {
 // search module (whichever one is currently active) to recognize the newly added word.
 // If adding multiple words, it is more efficient to pass false here in all but the last word.
 //
-// Returns the internal ID (>= 0) of the newly added word.
-func (d *Decoder) AddWord(word, phones String, update bool) (id int32, ok bool) {
+// Returns the internal ID (>= 0) of the newly added word. Reports ErrWordExists
+// if the word is already in the dictionary and ErrBadPhone if the pronunciation
+// contains phones unknown to the acoustic model.
+func (d *Decoder) AddWord(word, phones String, update bool) (id int32, err error) {
+	defer d.trackLog()()
+	mark := markErrors()
 	ret := pocketsphinx.AddWord(d.dec, word.S(), phones.S(), b(update))
 	if ret < 0 {
-		return 0, false
+		if _, ok := d.LookupWord(word); ok {
+			return 0, newError(mark, "AddWord", string(word), ErrWordExists)
+		}
+		return 0, newError(mark, "AddWord", string(word), ErrBadPhone)
 	}
-	return ret, true
+	d.words = append(d.words, dictWord{word: string(word), phones: string(phones)})
+	return ret, nil
 }
 
 // LookupWord lookups for the word in the dictionary and returns phone transcription for it.
@@ -149,34 +227,59 @@ func (d *Decoder) AddWord(word, phones String, update bool) (id int32, ok bool)
 // Returns whitespace-spearated phone string describing the pronunciation of the word,
 // or empty string if word is not present in the dictionary.
 func (d *Decoder) LookupWord(word String) (string, bool) {
-	phones := pocketsphinx.LookupWord(d.dec, word.S())
-	if phones != nil {
-		return string(phones), true
-	}
-	return "", false
+	return pocketsphinx.LookupWordPhones(d.dec, word.S())
 }
 
 // StartStream starts processing of the stream of speech. Channel parameters like
 // noise-level are maintained for the stream and reused among utterances.
 // Times returned in segment iterators are also stream-wide.
-func (d *Decoder) StartStream() bool {
+func (d *Decoder) StartStream() error {
+	defer d.trackLog()()
+	mark := markErrors()
 	ret := pocketsphinx.StartStream(d.dec)
-	return ret == 0
+	if ret < 0 {
+		return newError(mark, "StartStream", "", ErrFailed)
+	}
+	return nil
 }
 
 // StartUtt starts utterance processing.
 // This function should be called before any utterance data is passed
 // to the decoder. It marks the start of a new utterance and
 // reinitializes internal data structures.
-func (d *Decoder) StartUtt() bool {
+func (d *Decoder) StartUtt() error {
+	defer d.trackLog()()
+	if d.uttStarted {
+		return &Error{Op: "StartUtt", Err: ErrAlreadyStarted}
+	}
+	mark := markErrors()
 	ret := pocketsphinx.StartUtt(d.dec)
-	return ret == 0
+	if ret < 0 {
+		return newError(mark, "StartUtt", "", ErrFailed)
+	}
+	d.uttStarted = true
+	return nil
 }
 
 // EndUtt ends utterance processing.
-func (d *Decoder) EndUtt() bool {
+func (d *Decoder) EndUtt() error {
+	defer d.trackLog()()
+	if !d.uttStarted {
+		return &Error{Op: "EndUtt", Err: ErrNotStarted}
+	}
+	mark := markErrors()
 	ret := pocketsphinx.EndUtt(d.dec)
-	return ret == 0
+	d.uttStarted = false
+	if ret < 0 {
+		return newError(mark, "EndUtt", "", ErrFailed)
+	}
+	return nil
+}
+
+// UttStarted checks if an utterance has been started with Decoder.StartUtt()
+// and not yet ended.
+func (d *Decoder) UttStarted() bool {
+	return d.uttStarted
 }
 
 // ProcessRaw decodes a raw audio stream.
@@ -195,12 +298,17 @@ func (d *Decoder) EndUtt() bool {
 // produce more accurate results.
 //
 // Returns number of frames of data searched.
-func (d *Decoder) ProcessRaw(data []int16, noSearch, fullUtterance bool) (frames int32, ok bool) {
+func (d *Decoder) ProcessRaw(data []int16, noSearch, fullUtterance bool) (frames int32, err error) {
+	defer d.trackLog()()
+	if !d.uttStarted {
+		return 0, &Error{Op: "ProcessRaw", Err: ErrNotStarted}
+	}
+	mark := markErrors()
 	frames = pocketsphinx.ProcessRaw(d.dec, data, uint(len(data)), b(noSearch), b(fullUtterance))
 	if frames < 0 {
-		return 0, false
+		return 0, newError(mark, "ProcessRaw", "", ErrFailed)
 	}
-	return frames, true
+	return frames, nil
 }
 
 // ProcessCep decodes acoustic feature data.
@@ -214,12 +322,17 @@ func (d *Decoder) ProcessRaw(data []int16, noSearch, fullUtterance bool) (frames
 // produce more accurate results.
 //
 // Returns number of frames of data searched.
-func (d *Decoder) ProcessCep(data [][]float32, noSearch, fullUtterance bool) (frames int32, ok bool) {
+func (d *Decoder) ProcessCep(data [][]float32, noSearch, fullUtterance bool) (frames int32, err error) {
+	defer d.trackLog()()
+	if !d.uttStarted {
+		return 0, &Error{Op: "ProcessCep", Err: ErrNotStarted}
+	}
+	mark := markErrors()
 	frames = pocketsphinx.ProcessCep(d.dec, data, int32(len(data)), b(noSearch), b(fullUtterance))
 	if frames < 0 {
-		return 0, false
+		return 0, newError(mark, "ProcessCep", "", ErrFailed)
 	}
-	return frames, true
+	return frames, nil
 }
 
 // FramesSearched gets the number of frames of data searched.
@@ -258,7 +371,7 @@ func (d *Decoder) Probability() int32 {
 
 // WordLattice gets the word lattice object containing all hypotheses so far.
 //
-// The pointer is owned by the decoder and you should not attempt to free it manually.
+// The lattice is owned by the decoder, closing it is a no-op.
 // It is only valid until the next utterance, unless you use
 // Lattice.Retain() to retain it.
 func (d *Decoder) WordLattice() *Lattice {
@@ -322,21 +435,3 @@ func (d *Decoder) RawData() []int16 {
 	pocketsphinx.GetRawdata(d.dec, d.rawdataBuf, &size)
 	return d.rawdataBuf[0][:size]
 }
-
-// SetKeyphrase associates keyword search with the provided name. Activate
-// with Decoder.SetSearch()
-func (d *Decoder) SetKeyphrase(name string, keyphrase string) int32 {
-	return pocketsphinx.SetKeyphrase(d.dec, name, keyphrase)
-}
-
-// SetKws associates keyword search with the provided file. Activate
-// with Decoder.SetSearch()
-func (d *Decoder) SetKws(name string, keyfile string) int32 {
-	return pocketsphinx.SetKws(d.dec, name, keyfile)
-}
-
-// SetSearch activates the named search. The search must be set
-// beforehand with Decoder.SetKeyphrase() or Decoder.SetKws()
-func (d *Decoder) SetSearch(name string) int32 {
-	return pocketsphinx.SetSearch(d.dec, name)
-}
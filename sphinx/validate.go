@@ -0,0 +1,281 @@
+package sphinx
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/xlab/pocketsphinx-go/pocketsphinx"
+)
+
+// ValidationError lists every problem found by a check like Config.Validate(),
+// so they can be fixed at once.
+type ValidationError struct {
+	Errors []*Error
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap gets the individual errors, so errors.Is and errors.As can match any of them.
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// checkOptionValue checks that v has a Go type the option can be set from by optToCommandLn.
+func checkOptionValue(name String, v interface{}) error {
+	def, ok := lookupArg(string(name))
+	if !ok || !strings.HasPrefix(string(name), "-") {
+		return ErrUnknownOption
+	}
+	var valid bool
+	switch v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		valid = def.kind() == pocketsphinx.ArgInteger
+	case float32, float64:
+		valid = def.kind() == pocketsphinx.ArgFloating
+	case bool:
+		valid = def.kind() == pocketsphinx.ArgBoolean
+	case string, String:
+		valid = def.kind() == pocketsphinx.ArgString
+	}
+	if !valid {
+		return fmt.Errorf("%w: %T for %s option", ErrInvalidValue, v, optionType(def.kind()))
+	}
+	return nil
+}
+
+// checkPending records the pending options that would be dropped by optToCommandLn.
+func (c *Config) checkPending() {
+	for name, v := range c.opt {
+		if err := checkOptionValue(name, v); err != nil {
+			if c.problems == nil {
+				c.problems = make(map[String]error)
+			}
+			c.problems[name] = err
+			continue
+		}
+		delete(c.problems, name)
+	}
+}
+
+// optionRange limits the value of a numeric option.
+type optionRange struct {
+	min, max float64
+	// exclusive excludes min from the range.
+	exclusive bool
+}
+
+var (
+	positive    = optionRange{min: 0, max: math.Inf(1), exclusive: true}
+	nonNegative = optionRange{min: 0, max: math.Inf(1)}
+	probability = optionRange{min: 0, max: 1}
+)
+
+var optionRanges = map[string]optionRange{
+	"-samprate":       positive,
+	"-frate":          positive,
+	"-wlen":           positive,
+	"-nfft":           positive,
+	"-nfilt":          positive,
+	"-ncep":           positive,
+	"-ceplen":         positive,
+	"-lowerf":         nonNegative,
+	"-upperf":         positive,
+	"-topn":           positive,
+	"-ds":             positive,
+	"-logbase":        {min: 1, max: math.Inf(1), exclusive: true},
+	"-beam":           probability,
+	"-wbeam":          probability,
+	"-pbeam":          probability,
+	"-lpbeam":         probability,
+	"-lponlybeam":     probability,
+	"-fwdflatbeam":    probability,
+	"-fwdflatwbeam":   probability,
+	"-pl_beam":        probability,
+	"-pl_pbeam":       probability,
+	"-pl_pip":         positive,
+	"-kws_threshold":  positive,
+	"-kws_plp":        positive,
+	"-kws_delay":      positive,
+	"-lw":             positive,
+	"-fwdflatlw":      positive,
+	"-bestpathlw":     positive,
+	"-wip":            positive,
+	"-pip":            positive,
+	"-uw":             positive,
+	"-nwpen":          positive,
+	"-silprob":        probability,
+	"-fillprob":       probability,
+	"-ascale":         positive,
+	"-vad_threshold":  nonNegative,
+	"-vad_prespeech":  nonNegative,
+	"-vad_postspeech": nonNegative,
+	"-latsize":        positive,
+	"-min_endfr":      nonNegative,
+	"-debug":          nonNegative,
+}
+
+var optionChoices = map[string][]string{
+	"-cmn":          {"none", "batch", "live"},
+	"-agc":          {"none", "max", "emax", "noise"},
+	"-input_endian": {"little", "big"},
+	"-transform":    {"legacy", "dct", "htk"},
+	"-warp_type":    {"inverse_linear", "affine", "piecewise_linear"},
+}
+
+// optionFiles lists the options naming files or directories read by the decoder.
+var optionFiles = map[string]bool{
+	"-hmm":        true,
+	"-dict":       false,
+	"-fdict":      false,
+	"-lm":         false,
+	"-lmctl":      false,
+	"-fsg":        false,
+	"-jsgf":       false,
+	"-kws":        false,
+	"-allphone":   false,
+	"-mllr":       false,
+	"-mdef":       false,
+	"-mean":       false,
+	"-var":        false,
+	"-tmat":       false,
+	"-mixw":       false,
+	"-sendump":    false,
+	"-lda":        false,
+	"-featparams": false,
+}
+
+// Validate checks the configuration before it is used to create a decoder: the option names
+// against the definitions of pocketsphinx, the value types and ranges, and that the model
+// files exist and are readable. All problems found are reported in a *ValidationError,
+// each as an *Error with the option name as Arg and one of ErrUnknownOption, ErrMissingOption,
+// ErrInvalidValue, ErrFileNotFound or ErrFileNotReadable.
+func (c *Config) Validate() error {
+	return c.validate(true)
+}
+
+// validate checks the configuration, the required options are only checked if required is set,
+// the front-end and the feature computer do not need the ones of the decoder.
+func (c *Config) validate(required bool) error {
+	ln := c.CommandLn()
+	var errs []*Error
+	add := func(name string, err error) {
+		errs = append(errs, &Error{Op: "Validate", Arg: name, Err: err})
+	}
+	for name, err := range c.problems {
+		add(string(name), err)
+	}
+
+	values := argValues(ln)
+	for _, def := range argDefs() {
+		if required && def.Type&pocketsphinx.ArgRequired != 0 && values[def.Name] == nil {
+			add(def.Name, ErrMissingOption)
+		}
+	}
+	for name, r := range optionRanges {
+		var f float64
+		switch v := values[name].(type) {
+		case int:
+			f = float64(v)
+		case float64:
+			f = v
+		default:
+			continue
+		}
+		if f < r.min || (r.exclusive && f == r.min) || f > r.max {
+			add(name, fmt.Errorf("%w: %v is out of range", ErrInvalidValue, values[name]))
+		}
+	}
+	if upper, ok := values["-upperf"].(float64); ok {
+		if lower, ok := values["-lowerf"].(float64); ok && lower >= upper {
+			add("-lowerf", fmt.Errorf("%w: %v is not below -upperf %v", ErrInvalidValue, lower, upper))
+		}
+		if rate, ok := values["-samprate"].(float64); ok && upper > rate/2 {
+			add("-upperf", fmt.Errorf("%w: %v is above the Nyquist frequency %v", ErrInvalidValue, upper, rate/2))
+		}
+	}
+	if nfft, ok := values["-nfft"].(int); ok && nfft > 0 && nfft&(nfft-1) != 0 {
+		add("-nfft", fmt.Errorf("%w: %d is not a power of two", ErrInvalidValue, nfft))
+	}
+	for name, choices := range optionChoices {
+		v, ok := values[name].(string)
+		if !ok {
+			continue
+		}
+		var valid bool
+		for _, choice := range choices {
+			valid = valid || v == choice
+		}
+		if !valid {
+			add(name, fmt.Errorf("%w: %q is not one of %s", ErrInvalidValue, v, strings.Join(choices, ", ")))
+		}
+	}
+
+	resolved := argValues(c.resolved())
+	for name, isDir := range optionFiles {
+		path, ok := values[name].(string)
+		if !ok {
+			// the acoustic model files are looked up in -hmm, the decoder needs mdef at least
+			hmm, _ := values["-hmm"].(string)
+			if name != "-mdef" || resolved["-mdef"] != nil || hmm == "" || checkReadable(hmm, true) != nil {
+				continue
+			}
+			add(name, fmt.Errorf("%w: no mdef in %s", ErrFileNotFound, hmm))
+			continue
+		}
+		if err := checkReadable(path, isDir); err != nil {
+			add(name, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Arg < errs[j].Arg
+	})
+	return &ValidationError{Errors: errs}
+}
+
+// checkReadable checks that path is a readable file, or a directory if dir is true.
+func checkReadable(path string, dir bool) error {
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		return fmt.Errorf("%w: %s", ErrFileNotFound, path)
+	case err != nil:
+		return fmt.Errorf("%w: %v", ErrFileNotReadable, err)
+	case dir && !info.IsDir():
+		return fmt.Errorf("%w: %s is not a directory", ErrFileNotReadable, path)
+	case !dir && info.IsDir():
+		return fmt.Errorf("%w: %s is a directory", ErrFileNotReadable, path)
+	}
+	if dir {
+		_, err = os.ReadDir(path)
+	} else {
+		var f *os.File
+		if f, err = os.Open(path); err == nil {
+			f.Close()
+		}
+	}
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return fmt.Errorf("%w: %s: permission denied", ErrFileNotReadable, path)
+		}
+		return fmt.Errorf("%w: %v", ErrFileNotReadable, err)
+	}
+	return nil
+}
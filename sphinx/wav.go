@@ -0,0 +1,409 @@
+package sphinx
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/xlab/pocketsphinx-go/pocketsphinx"
+)
+
+// WAVFormat describes the audio format of a WAV file.
+type WAVFormat struct {
+	// SampleRate in Hz.
+	SampleRate int
+	// Channels is the number of interleaved channels.
+	Channels int
+	// BitsPerSample is the size of a single sample of a channel.
+	BitsPerSample int
+	// Float is set for IEEE floating point samples, otherwise samples are integer PCM.
+	Float bool
+}
+
+func (f WAVFormat) String() string {
+	kind := "PCM"
+	if f.Float {
+		kind = "float"
+	}
+	return fmt.Sprintf("%s%d %d Hz %dch", kind, f.BitsPerSample, f.SampleRate, f.Channels)
+}
+
+// WAV format tags, see mmreg.h.
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatFloat      = 0x0003
+	wavFormatExtensible = 0xFFFE
+)
+
+// WAVReader reads audio from a RIFF or RF64 WAV stream, converting it to 16-bit signed
+// mono PCM. Supported formats are 8, 16, 24 and 32-bit integer PCM and 32 or 64-bit float,
+// channels are downmixed by averaging.
+//
+// WAVReader implements io.Reader producing little-endian samples, so it can be passed
+// directly to Recognizer.Run() or Transcribe().
+type WAVReader struct {
+	r      io.Reader
+	format WAVFormat
+	// remaining is the number of bytes left in the data chunk, or -1 if unknown.
+	remaining  int64
+	blockAlign int
+
+	raw     []byte
+	samples []int16
+
+	// rs converts the audio to the sample rate set with ResampleTo.
+	rs      *Resampler
+	frames  []int16
+	pending []int16
+	out     []int16
+	eof     bool
+}
+
+// NewWAVReader parses the WAV header from r and positions it at the beginning of the audio data.
+func NewWAVReader(r io.Reader) (*WAVReader, error) {
+	w := &WAVReader{
+		r: r,
+	}
+	if err := w.readHeader(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Format returns the audio format of the stream.
+func (w *WAVReader) Format() WAVFormat {
+	return w.format
+}
+
+func (w *WAVReader) readHeader() error {
+	var hdr [12]byte
+	if _, err := io.ReadFull(w.r, hdr[:]); err != nil {
+		return wavError("missing RIFF header", err)
+	}
+	riff := string(hdr[0:4])
+	riffSize := binary.LittleEndian.Uint32(hdr[4:8])
+	if (riff != "RIFF" && riff != "RF64") || string(hdr[8:12]) != "WAVE" {
+		return wavError("not a RIFF WAVE stream", nil)
+	}
+	var haveFormat bool
+	var ds64DataSize int64 = -1
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(w.r, chunk[:]); err != nil {
+			return wavError("missing data chunk", err)
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		switch id {
+		case "ds64":
+			body, err := w.readChunk(size)
+			if err != nil {
+				return err
+			}
+			if len(body) < 16 {
+				return wavError("ds64 chunk is too short", nil)
+			}
+			ds64DataSize = int64(binary.LittleEndian.Uint64(body[8:16]))
+		case "fmt ":
+			body, err := w.readChunk(size)
+			if err != nil {
+				return err
+			}
+			if err := w.parseFormat(body); err != nil {
+				return err
+			}
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return wavError("data chunk before fmt chunk", nil)
+			}
+			w.remaining = size
+			if riff == "RF64" && size == 0xFFFFFFFF && ds64DataSize >= 0 {
+				w.remaining = ds64DataSize
+			} else if size == 0xFFFFFFFF || (size == 0 && (riffSize == 0 || riffSize == 0xFFFFFFFF)) {
+				// streamed WAV with unknown length, any other zero size is an empty data chunk
+				w.remaining = -1
+			}
+			return nil
+		default:
+			if _, err := w.readChunk(size); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readChunk reads a chunk body along with the padding byte for odd sizes.
+func (w *WAVReader) readChunk(size int64) ([]byte, error) {
+	if size > 1<<20 {
+		// skip large chunks we are not interested in
+		if _, err := io.CopyN(io.Discard, w.r, size+size%2); err != nil {
+			return nil, wavError("truncated chunk", err)
+		}
+		return nil, nil
+	}
+	body := make([]byte, size+size%2)
+	if _, err := io.ReadFull(w.r, body); err != nil {
+		return nil, wavError("truncated chunk", err)
+	}
+	return body[:size], nil
+}
+
+func (w *WAVReader) parseFormat(body []byte) error {
+	if len(body) < 16 {
+		return wavError("fmt chunk is too short", nil)
+	}
+	tag := binary.LittleEndian.Uint16(body[0:2])
+	w.format.Channels = int(binary.LittleEndian.Uint16(body[2:4]))
+	w.format.SampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+	w.blockAlign = int(binary.LittleEndian.Uint16(body[12:14]))
+	w.format.BitsPerSample = int(binary.LittleEndian.Uint16(body[14:16]))
+	if tag == wavFormatExtensible {
+		if len(body) < 26 {
+			return wavError("extensible fmt chunk is too short", nil)
+		}
+		// the first two bytes of the sub-format GUID hold the format tag
+		tag = binary.LittleEndian.Uint16(body[24:26])
+	}
+	switch tag {
+	case wavFormatPCM:
+		switch w.format.BitsPerSample {
+		case 8, 16, 24, 32:
+		default:
+			return unsupportedWAV(w.format)
+		}
+	case wavFormatFloat:
+		w.format.Float = true
+		switch w.format.BitsPerSample {
+		case 32, 64:
+		default:
+			return unsupportedWAV(w.format)
+		}
+	default:
+		return &Error{
+			Op:  "NewWAVReader",
+			Err: fmt.Errorf("%w: format tag 0x%04x", ErrUnsupportedFormat, tag),
+		}
+	}
+	if w.format.Channels < 1 || w.format.SampleRate < 1 ||
+		w.blockAlign != w.format.Channels*w.format.BitsPerSample/8 {
+		return wavError("inconsistent fmt chunk", nil)
+	}
+	return nil
+}
+
+// ResampleTo makes the reader convert the audio to the given sample rate, it must be
+// called before reading any samples. Format still reports the rate of the stream.
+func (w *WAVReader) ResampleTo(rate int, quality ResampleQuality) error {
+	if rate == w.format.SampleRate {
+		w.rs = nil
+		return nil
+	}
+	rs, err := NewResampler(w.format.SampleRate, rate, quality)
+	if err != nil {
+		return err
+	}
+	w.rs = rs
+	return nil
+}
+
+// ReadSamples reads up to len(dst) mono samples, returns the number of samples read.
+// At the end of the audio data it returns 0, io.EOF.
+func (w *WAVReader) ReadSamples(dst []int16) (int, error) {
+	if w.rs == nil {
+		return w.readFrames(dst)
+	}
+	for len(w.pending) == 0 {
+		if w.eof {
+			return 0, io.EOF
+		}
+		if len(w.frames) == 0 {
+			w.frames = make([]int16, 4096)
+		}
+		n, err := w.readFrames(w.frames)
+		w.out = w.rs.Process(w.frames[:n], w.out[:0])
+		if err == io.EOF {
+			w.out = w.rs.Flush(w.out)
+			w.eof = true
+		} else if err != nil {
+			return 0, err
+		}
+		w.pending = w.out
+	}
+	n := copy(dst, w.pending)
+	w.pending = w.pending[n:]
+	return n, nil
+}
+
+// readFrames reads up to len(dst) frames and downmixes them.
+func (w *WAVReader) readFrames(dst []int16) (int, error) {
+	if len(dst) == 0 {
+		return 0, nil
+	}
+	size := int64(len(dst) * w.blockAlign)
+	if w.remaining >= 0 && size > w.remaining {
+		size = w.remaining - w.remaining%int64(w.blockAlign)
+	}
+	if size == 0 {
+		return 0, io.EOF
+	}
+	if int64(cap(w.raw)) < size {
+		w.raw = make([]byte, size)
+	}
+	n, err := io.ReadFull(w.r, w.raw[:size])
+	if w.remaining >= 0 {
+		w.remaining -= int64(n)
+	}
+	frames := n / w.blockAlign
+	for i := 0; i < frames; i++ {
+		dst[i] = w.downmix(w.raw[i*w.blockAlign : (i+1)*w.blockAlign])
+	}
+	switch {
+	case err == io.ErrUnexpectedEOF || (err == io.EOF && frames > 0):
+		w.remaining = 0
+		return frames, nil
+	case err != nil:
+		return frames, err
+	}
+	return frames, nil
+}
+
+// Read reads 16-bit signed little-endian mono PCM into p.
+func (w *WAVReader) Read(p []byte) (int, error) {
+	count := len(p) / 2
+	if count == 0 {
+		return 0, io.ErrShortBuffer
+	}
+	if cap(w.samples) < count {
+		w.samples = make([]int16, count)
+	}
+	n, err := w.ReadSamples(w.samples[:count])
+	for i := 0; i < n; i++ {
+		binary.LittleEndian.PutUint16(p[i*2:], uint16(w.samples[i]))
+	}
+	return n * 2, err
+}
+
+// ReadAll reads all the remaining audio data.
+func (w *WAVReader) ReadAll() ([]int16, error) {
+	var all []int16
+	buf := make([]int16, 8192)
+	for {
+		n, err := w.ReadSamples(buf)
+		all = append(all, buf[:n]...)
+		if err == io.EOF {
+			return all, nil
+		} else if err != nil {
+			return all, err
+		}
+	}
+}
+
+// downmix converts a single frame to int16 averaging all channels.
+func (w *WAVReader) downmix(frame []byte) int16 {
+	width := w.format.BitsPerSample / 8
+	var sum float64
+	for ch := 0; ch < w.format.Channels; ch++ {
+		sum += w.sample(frame[ch*width : (ch+1)*width])
+	}
+	return clampInt16(sum / float64(w.format.Channels))
+}
+
+// sample converts a single sample to the int16 scale.
+func (w *WAVReader) sample(b []byte) float64 {
+	if w.format.Float {
+		if len(b) == 8 {
+			return math.Float64frombits(binary.LittleEndian.Uint64(b)) * 32768
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))) * 32768
+	}
+	switch len(b) {
+	case 1:
+		// 8-bit PCM is unsigned
+		return float64(int(b[0])-128) * 256
+	case 2:
+		return float64(int16(binary.LittleEndian.Uint16(b)))
+	case 3:
+		v := int32(b[0])<<8 | int32(b[1])<<16 | int32(b[2])<<24
+		return float64(v) / 65536
+	default:
+		return float64(int32(binary.LittleEndian.Uint32(b))) / 65536
+	}
+}
+
+func clampInt16(v float64) int16 {
+	v = math.Round(v)
+	if v > math.MaxInt16 {
+		return math.MaxInt16
+	} else if v < math.MinInt16 {
+		return math.MinInt16
+	}
+	return int16(v)
+}
+
+func wavError(msg string, err error) error {
+	if err != nil {
+		msg += ": " + err.Error()
+	}
+	return &Error{
+		Op:  "NewWAVReader",
+		Err: fmt.Errorf("%w: %s", ErrInvalidWAV, msg),
+	}
+}
+
+func unsupportedWAV(format WAVFormat) error {
+	return &Error{
+		Op:  "NewWAVReader",
+		Err: fmt.Errorf("%w: %s", ErrUnsupportedFormat, format),
+	}
+}
+
+// SampleRate gets the sampling rate of the audio the decoder expects (-samprate).
+func (d *Decoder) SampleRate() float64 {
+	return pocketsphinx.CommandLnFloatR(pocketsphinx.GetConfig(d.dec), String("-samprate").S())
+}
+
+// DecodeWAV decodes the whole WAV file as a single utterance. The audio is resampled
+// if the sample rate of the file does not match the sample rate of the decoder.
+func (d *Decoder) DecodeWAV(filename string) (Utterance, error) {
+	if err := checkFile("DecodeWAV", String(filename)); err != nil {
+		return Utterance{}, err
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return Utterance{}, &Error{Op: "DecodeWAV", Arg: filename, Err: err}
+	}
+	defer f.Close()
+	w, err := NewWAVReader(f)
+	if err != nil {
+		if e, ok := err.(*Error); ok {
+			e.Op, e.Arg = "DecodeWAV", filename
+		}
+		return Utterance{}, err
+	}
+	if err := w.ResampleTo(int(d.SampleRate()), ResampleDefault); err != nil {
+		return Utterance{}, err
+	}
+	samples, err := w.ReadAll()
+	if err != nil {
+		return Utterance{}, &Error{Op: "DecodeWAV", Arg: filename, Err: err}
+	}
+	return d.decodeSamples(samples)
+}
+
+// decodeSamples decodes samples as a full utterance.
+func (d *Decoder) decodeSamples(samples []int16) (Utterance, error) {
+	if err := d.StartUtt(); err != nil {
+		return Utterance{}, err
+	}
+	if _, err := d.ProcessRaw(samples, false, true); err != nil {
+		d.EndUtt()
+		return Utterance{}, err
+	}
+	if err := d.EndUtt(); err != nil {
+		return Utterance{}, err
+	}
+	return d.utterance(), nil
+}
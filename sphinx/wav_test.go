@@ -0,0 +1,91 @@
+package sphinx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// wavFile builds a RIFF WAVE stream of 16 kHz mono 16-bit PCM with the given sizes
+// in the RIFF and data chunk headers, followed by the extra bytes.
+func wavFile(riffSize, dataSize uint32, samples []int16, extra []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, riffSize)
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, []uint32{16, 1 | 1<<16, 16000, 32000, 2 | 16<<16})
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, dataSize)
+	binary.Write(&b, binary.LittleEndian, samples)
+	b.Write(extra)
+	return b.Bytes()
+}
+
+func TestWAVReader(t *testing.T) {
+	samples := []int16{1, -2, 300, -32768, 32767}
+	size := uint32(len(samples) * 2)
+	// a LIST chunk after the audio data must not be read as samples
+	list := []byte("LIST\x04\x00\x00\x00INFO")
+	cases := []struct {
+		name     string
+		data     []byte
+		expected []int16
+	}{
+		{"sized", wavFile(36+size, size, samples, list), samples},
+		{"truncated", wavFile(36+size+2, size+2, samples, nil), samples},
+		{"empty", wavFile(36+12, 0, nil, list), nil},
+		{"streamed", wavFile(0xFFFFFFFF, 0xFFFFFFFF, samples, nil), samples},
+		{"streamed with zero sizes", wavFile(0, 0, samples, nil), samples},
+		{"streamed with unknown RIFF size", wavFile(0xFFFFFFFF, 0, samples, nil), samples},
+	}
+	for _, tc := range cases {
+		w, err := NewWAVReader(bytes.NewReader(tc.data))
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if f := w.Format(); f != (WAVFormat{SampleRate: 16000, Channels: 1, BitsPerSample: 16}) {
+			t.Errorf("%s: format %v", tc.name, f)
+		}
+		got, err := w.ReadAll()
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if len(got) != len(tc.expected) {
+			t.Errorf("%s: got %v, expected %v", tc.name, got, tc.expected)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.expected[i] {
+				t.Errorf("%s: got %v, expected %v", tc.name, got, tc.expected)
+				break
+			}
+		}
+	}
+}
+
+func TestWAVReaderInvalid(t *testing.T) {
+	valid := wavFile(38, 2, []int16{0}, nil)
+	cases := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"empty", nil, ErrInvalidWAV},
+		{"not RIFF", append([]byte("RIFX"), valid[4:]...), ErrInvalidWAV},
+		{"no data chunk", valid[:36], ErrInvalidWAV},
+		{"data before fmt", append(valid[:12:12], valid[36:]...), ErrInvalidWAV},
+		{"float16", func() []byte {
+			b := append([]byte(nil), valid...)
+			b[20] = wavFormatFloat
+			return b
+		}(), ErrUnsupportedFormat},
+	}
+	for _, tc := range cases {
+		if _, err := NewWAVReader(bytes.NewReader(tc.data)); !errors.Is(err, tc.err) {
+			t.Errorf("%s: got %v, expected %v", tc.name, err, tc.err)
+		}
+	}
+}